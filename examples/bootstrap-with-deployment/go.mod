@@ -3,7 +3,6 @@ module github.com/loft-sh/vcluster-mydeployment-example
 go 1.25.0
 
 require (
-	github.com/loft-sh/vcluster v0.33.1
 	github.com/loft-sh/vcluster-sdk v0.6.9-0.20260402132606-8e58021df5c5
 	k8s.io/klog/v2 v2.130.1
 )
@@ -80,6 +79,7 @@ require (
 	github.com/go-openapi/jsonpointer v0.21.0 // indirect
 	github.com/go-openapi/jsonreference v0.21.0 // indirect
 	github.com/go-openapi/swag v0.23.1 // indirect
+	github.com/go-task/slim-sprig/v3 v3.0.0 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
 	github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
 	github.com/golang/protobuf v1.5.4 // indirect
@@ -119,6 +119,7 @@ require (
 	github.com/loft-sh/api/v4 v4.8.0-alpha.1 // indirect
 	github.com/loft-sh/apiserver v0.0.0-20260113122925-594495a02e96 // indirect
 	github.com/loft-sh/log v0.0.0-20240219160058-26d83ffb46ac // indirect
+	github.com/loft-sh/vcluster v0.33.1 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
@@ -235,3 +236,5 @@ require (
 	sigs.k8s.io/structured-merge-diff/v6 v6.3.0 // indirect
 	sigs.k8s.io/yaml v1.6.0 // indirect
 )
+
+replace github.com/loft-sh/vcluster-sdk => ../../
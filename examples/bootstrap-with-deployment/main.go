@@ -1,12 +1,44 @@
 package main
 
 import (
-	"github.com/loft-sh/vcluster-mydeployment-example/syncers"
 	"github.com/loft-sh/vcluster-sdk/plugin"
+	"github.com/loft-sh/vcluster-sdk/syncers"
+	"k8s.io/klog/v2"
 )
 
+type PluginConfig struct {
+	// Image is the image of the deployment
+	Image string `json:"image,omitempty"`
+
+	// Replicas is the number of replicas of the deployment
+	Replicas int `json:"replicas,omitempty"`
+}
+
 func main() {
-	ctx := plugin.MustInit()
-	plugin.MustRegister(syncers.NewMyDeploymentSyncer(ctx))
+	// Init plugin
+	plugin.MustInit()
+
+	// parse plugin config
+	pConfig := &PluginConfig{
+		Image:    "k8s.gcr.io/pause:3.2",
+		Replicas: 1,
+	}
+	err := plugin.UnmarshalConfig(pConfig)
+	if err != nil {
+		klog.Fatalf("unmarshal config: %v", err)
+	}
+
+	// render and apply the manifests, changes in the vCluster are reverted
+	manifests, err := syncers.NewManifests(syncers.ManifestsOptions{
+		Name:   "bootstrap-with-deployment",
+		Paths:  []string{"./manifests"},
+		Values: pConfig,
+	})
+	if err != nil {
+		klog.Fatalf("load manifests: %v", err)
+	}
+	plugin.MustRegister(manifests)
+
+	// start plugin
 	plugin.MustStart()
 }
@@ -1,16 +1,33 @@
 package main
 
 import (
-	"github.com/loft-sh/vcluster-import-secrets/syncers"
 	"github.com/loft-sh/vcluster-sdk/plugin"
+	"github.com/loft-sh/vcluster-sdk/syncers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
 )
 
+type PluginConfig struct {
+	// Mappings map host secrets to virtual secrets, e.g. "": "imported" imports all secrets
+	// of the vCluster host namespace into the imported namespace
+	Mappings map[string]string `json:"mappings,omitempty"`
+}
+
 func main() {
 	// Init plugin
-	ctx := plugin.MustInit()
+	plugin.MustInit()
+
+	// parse plugin config
+	pConfig := &PluginConfig{}
+	err := plugin.UnmarshalConfig(pConfig)
+	if err != nil {
+		klog.Fatalf("unmarshal config: %v", err)
+	}
 
-	// register syncer
-	plugin.MustRegister(syncers.NewImportSecrets(ctx))
+	// import the secrets
+	plugin.MustImportFromHost(corev1.SchemeGroupVersion.WithKind("Secret"), syncers.FromHostOptions{
+		Mappings: pConfig.Mappings,
+	})
 
 	// start plugin
 	plugin.MustStart()
@@ -0,0 +1,451 @@
+package interceptor
+
+import (
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	v2 "github.com/loft-sh/vcluster/pkg/plugin/v2"
+	"github.com/loft-sh/vcluster/pkg/scheme"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/kube-openapi/pkg/handler3"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// DefaultResourceVerbs are the verbs resources of a virtual api group support, if
+// ResourceOptions.Verbs is empty
+var DefaultResourceVerbs = []string{"get", "list", "watch", "create", "update", "delete"}
+
+// ResourceOptions describe how a resource is exposed in the discovery and OpenAPI documents
+// of a virtual api group
+type ResourceOptions struct {
+	// Plural is the plural name of the resource used in the url, e.g. podmetrics. Defaults
+	// to the guessed plural of the kind.
+	Plural string
+
+	// Singular is the singular name of the resource. Defaults to the lower case kind.
+	Singular string
+
+	// Namespaced defines if the resource is namespaced
+	Namespaced bool
+
+	// Verbs are the verbs the resource supports, defaults to DefaultResourceVerbs
+	Verbs []string
+
+	// ShortNames are short names of the resource, e.g. used by kubectl get
+	ShortNames []string
+
+	// Categories the resource belongs to, e.g. all
+	Categories []string
+
+	// Schema is the OpenAPI schema of the resource. Defaults to an object schema that
+	// preserves unknown fields.
+	Schema *spec.Schema
+}
+
+// APIGroupBuilder builds an interceptor that serves virtual api groups, which are not backed
+// by CRDs but by resource interceptors. Besides the resource requests, the interceptor serves
+// the discovery documents of the api groups and their OpenAPI v3 schemas and adds them
+// to the discovery and OpenAPI documents of the virtual cluster api server. The types of the
+// resources have to be added to the vCluster scheme. As the interceptor intercepts the
+// discovery of the api server, a plugin can only register one, so add all resources of
+// all virtual api groups to the same builder.
+type APIGroupBuilder struct {
+	name   string
+	config *rest.Config
+
+	resources []*apiResource
+	errs      []error
+}
+
+type apiResource struct {
+	gvk      schema.GroupVersionKind
+	resource metav1.APIResource
+	schema   *spec.Schema
+	handler  http.Handler
+}
+
+// NewAPIGroupBuilder creates a new builder for an interceptor with the given name. The
+// discovery and OpenAPI documents of the virtual cluster api server, which the documents
+// of the virtual api groups are added to, are retrieved with the virtual cluster client of
+// the plugin. vCluster authorizes the requests of the user before they are intercepted and
+// the documents are the same for all users.
+func NewAPIGroupBuilder(ctx *synccontext.RegisterContext, name string) *APIGroupBuilder {
+	return &APIGroupBuilder{
+		name:   name,
+		config: ctx.VirtualManager.GetConfig(),
+	}
+}
+
+// AddResource adds the resource handled by the given resource interceptor to the api group
+// of its type. The interception rules of the resource interceptor are ignored, as the
+// builder intercepts all requests for the resource.
+func AddResource[T client.Object](builder *APIGroupBuilder, resourceInterceptor ResourceInterceptor[T], options ResourceOptions) *APIGroupBuilder {
+	gvk, err := apiutil.GVKForObject(resourceInterceptor.Resource(), scheme.Scheme)
+	if err != nil {
+		builder.errs = append(builder.errs, fmt.Errorf("resource of %s: %w", resourceInterceptor.Name(), err))
+		return builder
+	} else if gvk.Group == "" {
+		builder.errs = append(builder.errs, fmt.Errorf("resource of %s: kind %s has no api group", resourceInterceptor.Name(), gvk.Kind))
+		return builder
+	}
+
+	plural, singular := meta.UnsafeGuessKindToResource(gvk)
+	if options.Plural != "" {
+		plural.Resource = options.Plural
+	}
+	if options.Singular != "" {
+		singular.Resource = options.Singular
+	}
+	verbs := options.Verbs
+	if len(verbs) == 0 {
+		verbs = DefaultResourceVerbs
+	}
+
+	builder.resources = append(builder.resources, &apiResource{
+		gvk: gvk,
+		resource: metav1.APIResource{
+			Name:         plural.Resource,
+			SingularName: singular.Resource,
+			Namespaced:   options.Namespaced,
+			Kind:         gvk.Kind,
+			Verbs:        verbs,
+			ShortNames:   options.ShortNames,
+			Categories:   options.Categories,
+		},
+		schema:  options.Schema,
+		handler: NewResourceInterceptor(resourceInterceptor),
+	})
+	return builder
+}
+
+// Build validates the added resources and returns the interceptor that can be registered
+// with the plugin
+func (b *APIGroupBuilder) Build() (plugin.Interceptor, error) {
+	if len(b.errs) > 0 {
+		return nil, errors.Join(b.errs...)
+	} else if len(b.resources) == 0 {
+		return nil, fmt.Errorf("api group interceptor %s has no resources", b.name)
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(b.config)
+	if err != nil {
+		return nil, fmt.Errorf("create discovery client: %w", err)
+	}
+
+	apiGroups := &apiGroupInterceptor{
+		name:         b.name,
+		client:       discoveryClient.RESTClient(),
+		groups:       map[string]*metav1.APIGroup{},
+		versions:     map[schema.GroupVersion]*metav1.APIResourceList{},
+		resources:    map[schema.GroupVersionResource]*apiResource{},
+		openAPI:      handler3.NewOpenAPIService(),
+		openAPIPaths: map[string]handler3.OpenAPIV3DiscoveryGroupVersion{},
+	}
+	for _, resource := range b.resources {
+		gvr := resource.gvk.GroupVersion().WithResource(resource.resource.Name)
+		if _, ok := apiGroups.resources[gvr]; ok {
+			return nil, fmt.Errorf("resource %s was added twice", gvr.String())
+		}
+		apiGroups.resources[gvr] = resource
+
+		resourceList, ok := apiGroups.versions[resource.gvk.GroupVersion()]
+		if !ok {
+			resourceList = &metav1.APIResourceList{GroupVersion: resource.gvk.GroupVersion().String()}
+			apiGroups.versions[resource.gvk.GroupVersion()] = resourceList
+		}
+		resourceList.APIResources = append(resourceList.APIResources, resource.resource)
+	}
+
+	// build the group discovery documents, sorted by version priority like the api server does
+	for groupVersion := range apiGroups.versions {
+		apiGroup, ok := apiGroups.groups[groupVersion.Group]
+		if !ok {
+			apiGroup = &metav1.APIGroup{Name: groupVersion.Group}
+			apiGroups.groups[groupVersion.Group] = apiGroup
+		}
+
+		apiGroup.Versions = append(apiGroup.Versions, metav1.GroupVersionForDiscovery{
+			GroupVersion: groupVersion.String(),
+			Version:      groupVersion.Version,
+		})
+	}
+	for _, apiGroup := range apiGroups.groups {
+		sort.Slice(apiGroup.Versions, func(i, j int) bool {
+			return version.CompareKubeAwareVersionStrings(apiGroup.Versions[i].Version, apiGroup.Versions[j].Version) > 0
+		})
+		apiGroup.PreferredVersion = apiGroup.Versions[0]
+	}
+
+	// build the OpenAPI documents
+	for groupVersion, resourceList := range apiGroups.versions {
+		openAPI := apiGroups.buildOpenAPI(groupVersion, resourceList)
+		openAPIPath := path.Join("apis", groupVersion.Group, groupVersion.Version)
+		apiGroups.openAPI.UpdateGroupVersion(openAPIPath, openAPI)
+		apiGroups.openAPIPaths[openAPIPath] = handler3.OpenAPIV3DiscoveryGroupVersion{
+			ServerRelativeURL: openAPIURL(openAPIPath, openAPI),
+		}
+	}
+
+	return apiGroups, nil
+}
+
+type apiGroupInterceptor struct {
+	name   string
+	client rest.Interface
+
+	groups    map[string]*metav1.APIGroup
+	versions  map[schema.GroupVersion]*metav1.APIResourceList
+	resources map[schema.GroupVersionResource]*apiResource
+
+	openAPI      *handler3.OpenAPIService
+	openAPIPaths map[string]handler3.OpenAPIV3DiscoveryGroupVersion
+}
+
+func (a *apiGroupInterceptor) Name() string {
+	return a.name
+}
+
+func (a *apiGroupInterceptor) InterceptionRules() []v2.InterceptorRule {
+	groups := sortedKeys(a.groups)
+	resources := []string{}
+	// the api group lists are intercepted as well, as clients only find the virtual api
+	// groups through them
+	nonResourceURLs := []string{"/apis", "/openapi/v3"}
+	for _, group := range groups {
+		nonResourceURLs = append(nonResourceURLs, "/apis/"+group)
+	}
+	for groupVersion := range a.versions {
+		nonResourceURLs = append(nonResourceURLs, "/apis/"+groupVersion.String(), "/openapi/v3/apis/"+groupVersion.String())
+	}
+	for gvr := range a.resources {
+		if !slices.Contains(resources, gvr.Resource) {
+			resources = append(resources, gvr.Resource)
+		}
+	}
+	sort.Strings(resources)
+	sort.Strings(nonResourceURLs)
+
+	return []v2.InterceptorRule{
+		{
+			APIGroups: groups,
+			Resources: resources,
+			Verbs:     []string{"*"},
+		},
+		{
+			NonResourceURLs: nonResourceURLs,
+			Verbs:           []string{"get"},
+		},
+	}
+}
+
+func (a *apiGroupInterceptor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	info, err := RequestInfo(r)
+	if err != nil {
+		WriteError(w, r, kerrors.NewBadRequest(err.Error()))
+		return
+	}
+
+	if info.IsResourceRequest {
+		resource, ok := a.resources[schema.GroupVersionResource{Group: info.APIGroup, Version: info.APIVersion, Resource: info.Resource}]
+		if !ok || info.Subresource != "" {
+			WriteError(w, r, kerrors.NewNotFound(schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}, info.Name))
+			return
+		}
+
+		resource.handler.ServeHTTP(w, r)
+		return
+	}
+
+	urlPath := strings.TrimSuffix(info.Path, "/")
+	switch {
+	case urlPath == "/apis":
+		a.mergeDiscovery(w, r, a.addGroups)
+		return
+	case urlPath == "/openapi/v3":
+		a.mergeDiscovery(w, r, a.addOpenAPIPaths)
+		return
+	case strings.HasPrefix(urlPath, "/openapi/v3/"):
+		if _, ok := a.openAPIPaths[strings.TrimPrefix(urlPath, "/openapi/v3/")]; ok {
+			a.openAPI.HandleGroupVersion(w, r)
+			return
+		}
+	case strings.HasPrefix(urlPath, "/apis/"):
+		parts := strings.Split(strings.TrimPrefix(urlPath, "/apis/"), "/")
+		if len(parts) == 1 && a.groups[parts[0]] != nil {
+			WriteObject(w, r, http.StatusOK, a.groups[parts[0]])
+			return
+		} else if len(parts) == 2 && a.versions[schema.GroupVersion{Group: parts[0], Version: parts[1]}] != nil {
+			WriteObject(w, r, http.StatusOK, a.versions[schema.GroupVersion{Group: parts[0], Version: parts[1]}])
+			return
+		}
+	}
+
+	WriteError(w, r, kerrors.NewNotFound(schema.GroupResource{}, info.Path))
+}
+
+// mergeDiscovery retrieves the json discovery document of the virtual cluster api server
+// for the request path and passes it to merge before returning it to the client
+func (a *apiGroupInterceptor) mergeDiscovery(w http.ResponseWriter, r *http.Request, merge func(body []byte) ([]byte, error)) {
+	// the aggregated discovery format cannot be merged, so always request the json document
+	body, err := a.client.Get().AbsPath(r.URL.Path).SetHeader("Accept", "application/json").Do(r.Context()).Raw()
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	body, err = merge(body)
+	if err != nil {
+		WriteError(w, r, kerrors.NewInternalError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// addGroups adds the virtual api groups to the api group list of the api server
+func (a *apiGroupInterceptor) addGroups(body []byte) ([]byte, error) {
+	groupList := &metav1.APIGroupList{}
+	err := json.Unmarshal(body, groupList)
+	if err != nil {
+		return nil, fmt.Errorf("decode api group list: %w", err)
+	}
+
+	for _, group := range sortedKeys(a.groups) {
+		groupList.Groups = slices.DeleteFunc(groupList.Groups, func(existing metav1.APIGroup) bool {
+			return existing.Name == group
+		})
+		groupList.Groups = append(groupList.Groups, *a.groups[group])
+	}
+
+	return json.Marshal(groupList)
+}
+
+// addOpenAPIPaths adds the OpenAPI documents of the virtual api groups to the OpenAPI v3
+// discovery document of the api server
+func (a *apiGroupInterceptor) addOpenAPIPaths(body []byte) ([]byte, error) {
+	discovery := &handler3.OpenAPIV3Discovery{}
+	err := json.Unmarshal(body, discovery)
+	if err != nil {
+		return nil, fmt.Errorf("decode OpenAPI discovery: %w", err)
+	}
+	if discovery.Paths == nil {
+		discovery.Paths = map[string]handler3.OpenAPIV3DiscoveryGroupVersion{}
+	}
+
+	for openAPIPath, groupVersion := range a.openAPIPaths {
+		discovery.Paths[openAPIPath] = groupVersion
+	}
+
+	return json.Marshal(discovery)
+}
+
+func (a *apiGroupInterceptor) buildOpenAPI(groupVersion schema.GroupVersion, resourceList *metav1.APIResourceList) *spec3.OpenAPI {
+	schemas := map[string]*spec.Schema{}
+	for _, apiResource := range resourceList.APIResources {
+		resource := a.resources[groupVersion.WithResource(apiResource.Name)]
+
+		resourceSchema := resource.schema
+		if resourceSchema == nil {
+			resourceSchema = &spec.Schema{
+				SchemaProps: spec.SchemaProps{
+					Type: []string{"object"},
+				},
+				VendorExtensible: spec.VendorExtensible{
+					Extensions: spec.Extensions{
+						"x-kubernetes-preserve-unknown-fields": true,
+					},
+				},
+			}
+		} else {
+			copied := *resourceSchema
+			resourceSchema = &copied
+		}
+
+		// kubectl uses the group version kind extension to find the schema of a kind
+		extensions := spec.Extensions{}
+		for key, value := range resourceSchema.Extensions {
+			extensions[key] = value
+		}
+		extensions["x-kubernetes-group-version-kind"] = []interface{}{
+			map[string]interface{}{
+				"group":   resource.gvk.Group,
+				"version": resource.gvk.Version,
+				"kind":    resource.gvk.Kind,
+			},
+		}
+		resourceSchema.Extensions = extensions
+
+		schemas[openAPIDefinitionName(resource.gvk)] = resourceSchema
+	}
+
+	return &spec3.OpenAPI{
+		Version: "3.0.0",
+		Info: &spec.Info{
+			InfoProps: spec.InfoProps{
+				Title:   a.name,
+				Version: groupVersion.Version,
+			},
+		},
+		Paths: &spec3.Paths{
+			Paths: map[string]*spec3.Path{},
+		},
+		Components: &spec3.Components{
+			Schemas: schemas,
+		},
+	}
+}
+
+// openAPIDefinitionName returns the name of the OpenAPI definition of the kind in the
+// format the api server uses for CRDs, e.g. com.example.v1.Report
+func openAPIDefinitionName(gvk schema.GroupVersionKind) string {
+	groupParts := strings.Split(gvk.Group, ".")
+	slices.Reverse(groupParts)
+	return strings.Join(append(groupParts, gvk.Version, gvk.Kind), ".")
+}
+
+// openAPIURL returns the url of the OpenAPI document including its hash the same way the
+// api server does. If the hash does not match, the client is redirected to the current one.
+func openAPIURL(openAPIPath string, openAPI *spec3.OpenAPI) string {
+	u := url.URL{Path: path.Join("/openapi/v3", openAPIPath)}
+
+	data, err := json.Marshal(openAPI)
+	if err == nil {
+		query := url.Values{}
+		query.Set("hash", fmt.Sprintf("%X", sha512.Sum512(data)))
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
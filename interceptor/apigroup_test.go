@@ -0,0 +1,103 @@
+package interceptor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/kube-openapi/pkg/handler3"
+)
+
+func TestAPIGroupInterceptorMergeDiscovery(t *testing.T) {
+	virtualGroup := &metav1.APIGroup{
+		Name:             "example.com",
+		Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "example.com/v1", Version: "v1"}},
+		PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "example.com/v1", Version: "v1"},
+	}
+
+	testCases := []struct {
+		name         string
+		path         string
+		serverStatus int
+		serverBody   string
+		expectedCode int
+		expected     string
+	}{
+		{
+			name:         "api groups",
+			path:         "/apis",
+			serverStatus: http.StatusOK,
+			serverBody:   `{"kind":"APIGroupList","apiVersion":"v1","groups":[{"name":"apps","versions":[],"preferredVersion":{"groupVersion":"apps/v1","version":"v1"}},{"name":"example.com","versions":[]}]}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"kind":"APIGroupList","apiVersion":"v1","groups":[{"name":"apps","versions":[],"preferredVersion":{"groupVersion":"apps/v1","version":"v1"}},{"name":"example.com","versions":[{"groupVersion":"example.com/v1","version":"v1"}],"preferredVersion":{"groupVersion":"example.com/v1","version":"v1"}}]}`,
+		},
+		{
+			name:         "openapi paths",
+			path:         "/openapi/v3",
+			serverStatus: http.StatusOK,
+			serverBody:   `{"paths":{"apis/apps/v1":{"serverRelativeURL":"/openapi/v3/apis/apps/v1?hash=A"}}}`,
+			expectedCode: http.StatusOK,
+			expected:     `{"paths":{"apis/apps/v1":{"serverRelativeURL":"/openapi/v3/apis/apps/v1?hash=A"},"apis/example.com/v1":{"serverRelativeURL":"/openapi/v3/apis/example.com/v1?hash=B"}}}`,
+		},
+		{
+			name:         "api server error",
+			path:         "/apis",
+			serverStatus: http.StatusForbidden,
+			serverBody:   `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403}`,
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != testCase.path {
+					t.Errorf("unexpected request to %s", r.URL.Path)
+				} else if r.Header.Get("Accept") != "application/json" {
+					t.Errorf("unexpected accept header %s", r.Header.Get("Accept"))
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(testCase.serverStatus)
+				_, _ = w.Write([]byte(testCase.serverBody))
+			}))
+			defer server.Close()
+
+			discoveryClient, err := discovery.NewDiscoveryClientForConfig(&rest.Config{Host: server.URL})
+			if err != nil {
+				t.Fatalf("create discovery client: %v", err)
+			}
+			a := &apiGroupInterceptor{
+				client: discoveryClient.RESTClient(),
+				groups: map[string]*metav1.APIGroup{"example.com": virtualGroup},
+				openAPIPaths: map[string]handler3.OpenAPIV3DiscoveryGroupVersion{
+					"apis/example.com/v1": {ServerRelativeURL: "/openapi/v3/apis/example.com/v1?hash=B"},
+				},
+			}
+
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, httptest.NewRequest("GET", testCase.path, nil))
+			if w.Code != testCase.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", testCase.expectedCode, w.Code, w.Body.String())
+			} else if testCase.expected == "" {
+				return
+			}
+
+			expected, actual := map[string]interface{}{}, map[string]interface{}{}
+			_ = json.Unmarshal([]byte(testCase.expected), &expected)
+			err = json.Unmarshal(w.Body.Bytes(), &actual)
+			if err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			expectedJSON, _ := json.Marshal(expected)
+			actualJSON, _ := json.Marshal(actual)
+			if string(expectedJSON) != string(actualJSON) {
+				t.Fatalf("expected %s, got %s", expectedJSON, actualJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package interceptor
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/utils/lru"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// maxRateLimiters is the number of rate limiters kept per limits interceptor. The least
+// recently used limiters are dropped first.
+const maxRateLimiters = 10000
+
+var (
+	rateLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vcluster_plugin_rate_limited_requests_total",
+		Help: "Total number of requests rejected by plugin rate limits.",
+	}, []string{"interceptor", "verb", "resource"})
+
+	objectLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vcluster_plugin_object_limited_requests_total",
+		Help: "Total number of create requests rejected by plugin object count limits.",
+	}, []string{"interceptor", "resource"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(rateLimitedRequests, objectLimitedRequests)
+}
+
+// LimitsConfig configures the rate and object count limits enforced by NewLimitsInterceptor.
+// vCluster does not pass the user of intercepted requests on to plugins, so the limits
+// apply to the virtual cluster as a whole and not per user. A single user can use up a
+// rate limit for all other users of the virtual cluster. It is meant to be part of the
+// plugin config, e.g.:
+//
+//	limits:
+//	  rateLimits:
+//	  - apiGroups: [""]
+//	    resources: ["configmaps"]
+//	    verbs: ["create", "update"]
+//	    qps: 5
+//	    burst: 10
+//	  objectLimits:
+//	  - apiGroup: ""
+//	    resource: configmaps
+//	    max: 100
+type LimitsConfig struct {
+	// RateLimits limit the requests per second to the selected resources
+	RateLimits []RateLimit `json:"rateLimits,omitempty"`
+
+	// ObjectLimits limit the number of objects per namespace
+	ObjectLimits []ObjectLimit `json:"objectLimits,omitempty"`
+}
+
+// RateLimit limits the requests that can be sent for the selected resources and verbs. Each
+// verb and resource gets its own limit, which is shared by all users of the virtual cluster.
+type RateLimit struct {
+	// APIGroups, Resources and Verbs select the requests the limit applies to, * selects all
+	APIGroups []string `json:"apiGroups,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+	Verbs     []string `json:"verbs,omitempty"`
+
+	// QPS is the number of requests per second that can be sent
+	QPS float64 `json:"qps,omitempty"`
+
+	// Burst is the number of requests that can be sent at once, defaults to QPS
+	Burst int `json:"burst,omitempty"`
+}
+
+// ObjectLimit limits the number of objects of a resource per namespace
+type ObjectLimit struct {
+	// APIGroup and Resource select the resource that is limited
+	APIGroup string `json:"apiGroup,omitempty"`
+	Resource string `json:"resource,omitempty"`
+
+	// Namespaces restricts the limit to the given namespaces, by default it applies to all
+	// namespaces
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Max is the maximum number of objects per namespace, 0 forbids creating objects
+	Max int `json:"max,omitempty"`
+}
+
+// NewLimitsInterceptor wraps the given interceptor, so the configured limits are enforced
+// before it serves a request. Requests exceeding a rate limit are rejected with too many
+// requests, creations exceeding an object limit with forbidden. Only requests the wrapped
+// interceptor intercepts are limited, as plugins cannot pass requests on to the virtual
+// cluster api server. Object limits are enforced on a best effort basis, concurrent
+// creations can exceed them.
+func NewLimitsInterceptor(ctx *synccontext.RegisterContext, config LimitsConfig, next plugin.Interceptor) (plugin.Interceptor, error) {
+	if next == nil {
+		return nil, fmt.Errorf("limits interceptor needs an interceptor to wrap")
+	} else if len(config.RateLimits) == 0 && len(config.ObjectLimits) == 0 {
+		return nil, fmt.Errorf("limits interceptor %s has no limits", next.Name())
+	}
+
+	for i, rateLimit := range config.RateLimits {
+		if len(rateLimit.APIGroups) == 0 || len(rateLimit.Resources) == 0 || len(rateLimit.Verbs) == 0 {
+			return nil, fmt.Errorf("rate limit %d: apiGroups, resources and verbs are required", i)
+		} else if rateLimit.QPS <= 0 {
+			return nil, fmt.Errorf("rate limit %d: qps has to be greater than 0", i)
+		}
+	}
+	for i, objectLimit := range config.ObjectLimits {
+		if objectLimit.Resource == "" || objectLimit.Resource == "*" || objectLimit.APIGroup == "*" {
+			return nil, fmt.Errorf("object limit %d: resource is required and cannot be a wildcard", i)
+		} else if objectLimit.Max < 0 {
+			return nil, fmt.Errorf("object limit %d: max cannot be negative", i)
+		}
+	}
+
+	return &limitsInterceptor{
+		Interceptor: next,
+		config:      config,
+
+		reader:     ctx.VirtualManager.GetAPIReader(),
+		restMapper: ctx.VirtualManager.GetRESTMapper(),
+		limiters:   lru.New(maxRateLimiters),
+	}, nil
+}
+
+type limitsInterceptor struct {
+	plugin.Interceptor
+
+	config LimitsConfig
+
+	reader     client.Reader
+	restMapper meta.RESTMapper
+
+	limitersLock sync.Mutex
+	limiters     *lru.Cache
+}
+
+func (l *limitsInterceptor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	info, err := RequestInfo(r)
+	if err != nil {
+		WriteError(w, r, kerrors.NewBadRequest(err.Error()))
+		return
+	}
+
+	err = l.checkRateLimits(info)
+	if err == nil && info.Verb == "create" {
+		err = l.checkObjectLimits(r, info)
+	}
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	l.Interceptor.ServeHTTP(w, r)
+}
+
+func (l *limitsInterceptor) checkRateLimits(info *request.RequestInfo) error {
+	reservations := []*rate.Reservation{}
+	for i, rateLimit := range l.config.RateLimits {
+		if !matchesValues(rateLimit.APIGroups, info.APIGroup) || !matchesValues(rateLimit.Resources, info.Resource) || !matchesValues(rateLimit.Verbs, info.Verb) {
+			continue
+		}
+
+		limiter := l.limiterFor(fmt.Sprintf("%d/%s/%s/%s", i, info.Verb, info.APIGroup, info.Resource), rateLimit)
+		reservation := limiter.Reserve()
+		reservations = append(reservations, reservation)
+		delay := reservation.Delay()
+		if delay == 0 {
+			continue
+		}
+
+		// the request is rejected, so give back the tokens of all limits
+		for _, reservation := range reservations {
+			reservation.Cancel()
+		}
+
+		rateLimitedRequests.WithLabelValues(l.Name(), info.Verb, groupResourceString(info)).Inc()
+		retryAfterSeconds := int(math.Ceil(delay.Seconds()))
+		return kerrors.NewTooManyRequests(fmt.Sprintf("rate limit of %v requests per second for %s %s exceeded", rateLimit.QPS, info.Verb, groupResourceString(info)), retryAfterSeconds)
+	}
+
+	return nil
+}
+
+func (l *limitsInterceptor) limiterFor(key string, rateLimit RateLimit) *rate.Limiter {
+	l.limitersLock.Lock()
+	defer l.limitersLock.Unlock()
+
+	limiter, ok := l.limiters.Get(key)
+	if ok {
+		return limiter.(*rate.Limiter)
+	}
+
+	burst := rateLimit.Burst
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(rateLimit.QPS)))
+	}
+
+	newLimiter := rate.NewLimiter(rate.Limit(rateLimit.QPS), burst)
+	l.limiters.Add(key, newLimiter)
+	return newLimiter
+}
+
+func (l *limitsInterceptor) checkObjectLimits(r *http.Request, info *request.RequestInfo) error {
+	for _, objectLimit := range l.config.ObjectLimits {
+		if objectLimit.APIGroup != info.APIGroup || objectLimit.Resource != info.Resource || info.Subresource != "" {
+			continue
+		} else if len(objectLimit.Namespaces) > 0 && !slices.Contains(objectLimit.Namespaces, info.Namespace) {
+			continue
+		}
+
+		count, err := l.countObjects(r, info)
+		if err != nil {
+			return err
+		} else if count < objectLimit.Max {
+			continue
+		}
+
+		objectLimitedRequests.WithLabelValues(l.Name(), groupResourceString(info)).Inc()
+		return kerrors.NewForbidden(schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}, info.Name, fmt.Errorf("exceeded object limit of %d %s in namespace %q", objectLimit.Max, groupResourceString(info), info.Namespace))
+	}
+
+	return nil
+}
+
+// countObjects counts the objects of the requested resource in the namespace of the request.
+// It reads from the api server instead of a cache, as interceptors are served by all
+// replicas, not only by the leader that starts the caches.
+func (l *limitsInterceptor) countObjects(r *http.Request, info *request.RequestInfo) (int, error) {
+	gvk, err := l.restMapper.KindFor(schema.GroupVersionResource{Group: info.APIGroup, Version: info.APIVersion, Resource: info.Resource})
+	if err != nil {
+		return 0, fmt.Errorf("find kind of %s: %w", groupResourceString(info), err)
+	}
+
+	list := &metav1.PartialObjectMetadataList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	err = l.reader.List(r.Context(), list, client.InNamespace(info.Namespace))
+	if err != nil {
+		return 0, fmt.Errorf("count %s: %w", groupResourceString(info), err)
+	}
+
+	return len(list.Items), nil
+}
+
+func matchesValues(values []string, value string) bool {
+	return slices.Contains(values, "*") || slices.Contains(values, value)
+}
+
+func groupResourceString(info *request.RequestInfo) string {
+	return schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}.String()
+}
@@ -0,0 +1,247 @@
+package interceptor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/utils/lru"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestCheckRateLimits(t *testing.T) {
+	createConfigMap := &request.RequestInfo{IsResourceRequest: true, Verb: "create", APIVersion: "v1", Resource: "configmaps", Namespace: "default"}
+	updateConfigMap := &request.RequestInfo{IsResourceRequest: true, Verb: "update", APIVersion: "v1", Resource: "configmaps", Namespace: "default"}
+	createSecret := &request.RequestInfo{IsResourceRequest: true, Verb: "create", APIVersion: "v1", Resource: "secrets", Namespace: "default"}
+
+	testCases := []struct {
+		name       string
+		rateLimits []RateLimit
+		requests   []*request.RequestInfo
+		limited    []bool
+	}{
+		{
+			name:       "burst is allowed",
+			rateLimits: []RateLimit{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create"}, QPS: 0.001, Burst: 2}},
+			requests:   []*request.RequestInfo{createConfigMap, createConfigMap, createConfigMap},
+			limited:    []bool{false, false, true},
+		},
+		{
+			name:       "burst defaults to qps",
+			rateLimits: []RateLimit{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create"}, QPS: 0.001}},
+			requests:   []*request.RequestInfo{createConfigMap, createConfigMap},
+			limited:    []bool{false, true},
+		},
+		{
+			name:       "unselected requests are not limited",
+			rateLimits: []RateLimit{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create"}, QPS: 0.001}},
+			requests:   []*request.RequestInfo{createConfigMap, updateConfigMap, createSecret, updateConfigMap},
+			limited:    []bool{false, false, false, false},
+		},
+		{
+			name:       "wildcards limit each verb and resource separately",
+			rateLimits: []RateLimit{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}, QPS: 0.001}},
+			requests:   []*request.RequestInfo{createConfigMap, updateConfigMap, createSecret, createConfigMap},
+			limited:    []bool{false, false, false, true},
+		},
+		{
+			name: "all matching limits apply",
+			rateLimits: []RateLimit{
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create"}, QPS: 0.001, Burst: 5},
+				{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"create"}, QPS: 0.001, Burst: 1},
+			},
+			requests: []*request.RequestInfo{createConfigMap, createConfigMap},
+			limited:  []bool{false, true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			l := &limitsInterceptor{
+				Interceptor: &servingInterceptor{},
+				config:      LimitsConfig{RateLimits: testCase.rateLimits},
+				limiters:    lru.New(maxRateLimiters),
+			}
+
+			for i, info := range testCase.requests {
+				err := l.checkRateLimits(info)
+				if testCase.limited[i] != kerrors.IsTooManyRequests(err) {
+					t.Fatalf("request %d: expected limited %v, got error %v", i, testCase.limited[i], err)
+				} else if !testCase.limited[i] && err != nil {
+					t.Fatalf("request %d: unexpected error %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckObjectLimits(t *testing.T) {
+	createConfigMap := &request.RequestInfo{IsResourceRequest: true, Verb: "create", APIVersion: "v1", Resource: "configmaps", Namespace: "default"}
+	createConfigMapStatus := &request.RequestInfo{IsResourceRequest: true, Verb: "create", APIVersion: "v1", Resource: "configmaps", Subresource: "status", Namespace: "default"}
+	createSecret := &request.RequestInfo{IsResourceRequest: true, Verb: "create", APIVersion: "v1", Resource: "secrets", Namespace: "default"}
+
+	testCases := []struct {
+		name         string
+		objectLimits []ObjectLimit
+		count        int
+		info         *request.RequestInfo
+		forbidden    bool
+	}{
+		{
+			name:         "below limit",
+			objectLimits: []ObjectLimit{{Resource: "configmaps", Max: 2}},
+			count:        1,
+			info:         createConfigMap,
+		},
+		{
+			name:         "limit reached",
+			objectLimits: []ObjectLimit{{Resource: "configmaps", Max: 2}},
+			count:        2,
+			info:         createConfigMap,
+			forbidden:    true,
+		},
+		{
+			name:         "zero forbids creation",
+			objectLimits: []ObjectLimit{{Resource: "configmaps"}},
+			info:         createConfigMap,
+			forbidden:    true,
+		},
+		{
+			name:         "other resource",
+			objectLimits: []ObjectLimit{{Resource: "configmaps"}},
+			info:         createSecret,
+		},
+		{
+			name:         "subresource",
+			objectLimits: []ObjectLimit{{Resource: "configmaps"}},
+			info:         createConfigMapStatus,
+		},
+		{
+			name:         "other namespace",
+			objectLimits: []ObjectLimit{{Resource: "configmaps", Namespaces: []string{"limited"}}},
+			info:         createConfigMap,
+		},
+		{
+			name:         "selected namespace",
+			objectLimits: []ObjectLimit{{Resource: "configmaps", Namespaces: []string{"default"}}},
+			info:         createConfigMap,
+			forbidden:    true,
+		},
+	}
+
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	restMapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, meta.RESTScopeNamespace)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			l := &limitsInterceptor{
+				Interceptor: &servingInterceptor{},
+				config:      LimitsConfig{ObjectLimits: testCase.objectLimits},
+				reader:      countingReader(testCase.count),
+				restMapper:  restMapper,
+			}
+
+			err := l.checkObjectLimits(httptest.NewRequest("POST", "/", nil), testCase.info)
+			if testCase.forbidden != kerrors.IsForbidden(err) {
+				t.Fatalf("expected forbidden %v, got error %v", testCase.forbidden, err)
+			} else if !testCase.forbidden && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestLimitsInterceptorServeHTTP(t *testing.T) {
+	next := &servingInterceptor{}
+	l := &limitsInterceptor{
+		Interceptor: next,
+		config: LimitsConfig{
+			RateLimits:   []RateLimit{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"list"}, QPS: 0.001}},
+			ObjectLimits: []ObjectLimit{{Resource: "configmaps", Max: 1}},
+		},
+		reader:     countingReader(1),
+		restMapper: meta.NewDefaultRESTMapper(nil),
+		limiters:   lru.New(maxRateLimiters),
+	}
+	l.restMapper.(*meta.DefaultRESTMapper).Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+
+	testCases := []struct {
+		name         string
+		method       string
+		url          string
+		expectedCode int
+	}{
+		{
+			name:         "allowed request is served",
+			method:       "GET",
+			url:          "/api/v1/namespaces/default/configmaps",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "rate limited request",
+			method:       "GET",
+			url:          "/api/v1/namespaces/default/configmaps",
+			expectedCode: http.StatusTooManyRequests,
+		},
+		{
+			name:         "object limited request",
+			method:       "POST",
+			url:          "/api/v1/namespaces/default/configmaps",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "unlimited request is served",
+			method:       "GET",
+			url:          "/api/v1/namespaces/default/configmaps/test",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			served := next.served
+			w := httptest.NewRecorder()
+			l.ServeHTTP(w, httptest.NewRequest(testCase.method, testCase.url, nil))
+			if w.Code != testCase.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", testCase.expectedCode, w.Code, w.Body.String())
+			} else if (testCase.expectedCode == http.StatusOK) != (next.served > served) {
+				t.Fatalf("expected wrapped interceptor to serve only allowed requests")
+			}
+		})
+	}
+}
+
+// servingInterceptor answers all requests with ok and counts them
+type servingInterceptor struct {
+	plugin.Interceptor
+
+	served int
+}
+
+func (s *servingInterceptor) Name() string {
+	return "test"
+}
+
+func (s *servingInterceptor) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.served++
+	w.WriteHeader(http.StatusOK)
+}
+
+// countingReader lists the given number of objects
+type countingReader int
+
+func (c countingReader) Get(_ context.Context, _ client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+	return nil
+}
+
+func (c countingReader) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	list.(*metav1.PartialObjectMetadataList).Items = make([]metav1.PartialObjectMetadata, int(c))
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package interceptor contains helpers to implement plugin interceptors. It parses the
+// intercepted requests, decodes request bodies into typed objects and writes responses
+// in the format the client negotiated.
+package interceptor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	"github.com/loft-sh/vcluster/pkg/scheme"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// Codecs are used to decode requests and encode responses. They are based on the vCluster
+// scheme, so types added to that scheme by the plugin can be used as well.
+var Codecs = serializer.NewCodecFactory(scheme.Scheme)
+
+// Request is an intercepted request together with its parsed request info
+type Request struct {
+	*http.Request
+
+	// Info holds the verb, api group, resource, namespace and name of the request
+	Info *request.RequestInfo
+}
+
+// NewRequest parses the request info of the given request
+func NewRequest(r *http.Request) (*Request, error) {
+	info, err := RequestInfo(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Request{
+		Request: r,
+		Info:    info,
+	}, nil
+}
+
+// RequestInfo returns the request info of the given request. If the request info was
+// not already added to the request context, it is parsed from the request url.
+func RequestInfo(r *http.Request) (*request.RequestInfo, error) {
+	return plugin.RequestInfo(r)
+}
+
+// DecodeBody decodes the request body into the given object. The request content type
+// decides which serializer is used. The body can be read again afterwards.
+func DecodeBody(r *http.Request, into runtime.Object) error {
+	serializerInfo, err := negotiation.NegotiateInputSerializer(r, false, Codecs)
+	if err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return kerrors.NewBadRequest(fmt.Sprintf("read request body: %v", err))
+	}
+
+	// restore the body, so it can be read again
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	_, _, err = serializerInfo.Serializer.Decode(body, nil, into)
+	if err != nil {
+		return kerrors.NewBadRequest(fmt.Sprintf("decode request body: %v", err))
+	}
+
+	return nil
+}
+
+// DecodeListOptions decodes the list options, e.g. label selectors, from the request query
+func DecodeListOptions(r *http.Request) (*metav1.ListOptions, error) {
+	query := r.URL.Query()
+	listOptions := &metav1.ListOptions{}
+	err := metav1.Convert_url_Values_To_v1_ListOptions(&query, listOptions, nil)
+	if err != nil {
+		return nil, kerrors.NewBadRequest(fmt.Sprintf("decode list options: %v", err))
+	}
+
+	return listOptions, nil
+}
@@ -0,0 +1,79 @@
+package interceptor
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestDecodeBody(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    map[string]string
+		errReason   func(err error) bool
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test"},"data":{"a":"b"}}`,
+			expected:    map[string]string{"a": "b"},
+		},
+		{
+			name:        "yaml",
+			contentType: "application/yaml",
+			body:        "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\ndata:\n  a: b\n",
+			expected:    map[string]string{"a": "b"},
+		},
+		{
+			name:        "json with charset",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test"}}`,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "text/plain",
+			body:        "test",
+			errReason:   kerrors.IsUnsupportedMediaType,
+		},
+		{
+			name:        "invalid body",
+			contentType: "application/json",
+			body:        `{"metadata":`,
+			errReason:   kerrors.IsBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/v1/namespaces/default/configmaps", strings.NewReader(testCase.body))
+			r.Header.Set("Content-Type", testCase.contentType)
+
+			configMap := &corev1.ConfigMap{}
+			err := DecodeBody(r, configMap)
+			if testCase.errReason != nil {
+				if !testCase.errReason(err) {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if configMap.Name != "test" || len(configMap.Data) != len(testCase.expected) || configMap.Data["a"] != testCase.expected["a"] {
+				t.Fatalf("unexpected object %v", configMap)
+			}
+
+			// the body can be read again
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != testCase.body {
+				t.Fatalf("expected body %q to be restored, got %q", testCase.body, string(body))
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package interceptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	v2 "github.com/loft-sh/vcluster/pkg/plugin/v2"
+	"github.com/loft-sh/vcluster/pkg/scheme"
+	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ResourceInterceptor handles intercepted requests for a single typed resource. Instead of
+// implementing http.Handler directly, the interceptor receives decoded objects and returns
+// typed results, which are encoded in the format the client negotiated. Returned kubernetes
+// api errors are passed on to the client as metav1.Status. vCluster passes on requests for
+// subresources of intercepted resources as well, see req.Info.Subresource. Patch requests
+// are served with Get and Update, delete collection requests with List and Delete. Embed
+// UnimplementedResourceInterceptor to only implement a subset of the methods.
+type ResourceInterceptor[T client.Object] interface {
+	syncertypes.Base
+
+	// Resource is the typed resource (e.g. &corev1.Pod{}) requests are decoded into
+	Resource() T
+
+	// InterceptionRules returns an rbac style struct which defines what to intercept
+	InterceptionRules() []v2.InterceptorRule
+
+	// Get is called for get requests
+	Get(req *Request) (T, error)
+
+	// List is called for list requests. Use DecodeListOptions to retrieve the label
+	// and field selectors of the request.
+	List(req *Request) (client.ObjectList, error)
+
+	// Create is called for create requests with the decoded object
+	Create(req *Request, obj T) (T, error)
+
+	// Update is called for update requests with the decoded object
+	Update(req *Request, obj T) (T, error)
+
+	// Delete is called for delete requests
+	Delete(req *Request) error
+
+	// Watch is called for watch requests. The returned watch is streamed to the client
+	// and stopped afterwards, see NewInformerWatcher to watch an informer.
+	Watch(req *Request) (watch.Interface, error)
+}
+
+// UnimplementedResourceInterceptor returns method not supported for all requests
+type UnimplementedResourceInterceptor[T client.Object] struct{}
+
+func (UnimplementedResourceInterceptor[T]) Get(req *Request) (T, error) {
+	var empty T
+	return empty, methodNotSupported(req)
+}
+
+func (UnimplementedResourceInterceptor[T]) List(req *Request) (client.ObjectList, error) {
+	return nil, methodNotSupported(req)
+}
+
+func (UnimplementedResourceInterceptor[T]) Create(req *Request, _ T) (T, error) {
+	var empty T
+	return empty, methodNotSupported(req)
+}
+
+func (UnimplementedResourceInterceptor[T]) Update(req *Request, _ T) (T, error) {
+	var empty T
+	return empty, methodNotSupported(req)
+}
+
+func (UnimplementedResourceInterceptor[T]) Delete(req *Request) error {
+	return methodNotSupported(req)
+}
+
+func (UnimplementedResourceInterceptor[T]) Watch(req *Request) (watch.Interface, error) {
+	return nil, methodNotSupported(req)
+}
+
+// NewResourceInterceptor creates a new interceptor that can be registered with the plugin
+// from the given resource interceptor.
+func NewResourceInterceptor[T client.Object](resourceInterceptor ResourceInterceptor[T]) plugin.Interceptor {
+	return &resourceHandler[T]{
+		ResourceInterceptor: resourceInterceptor,
+	}
+}
+
+type resourceHandler[T client.Object] struct {
+	ResourceInterceptor[T]
+}
+
+func (h *resourceHandler[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := NewRequest(r)
+	if err != nil {
+		WriteError(w, r, kerrors.NewBadRequest(err.Error()))
+		return
+	} else if !req.Info.IsResourceRequest {
+		WriteError(w, r, kerrors.NewBadRequest("expected a resource request"))
+		return
+	}
+
+	switch req.Info.Verb {
+	case "get":
+		obj, err := h.Get(req)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		WriteObject(w, r, http.StatusOK, obj)
+	case "list":
+		list, err := h.List(req)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		WriteObject(w, r, http.StatusOK, list)
+	case "create":
+		obj := h.Resource().DeepCopyObject().(T)
+		err := DecodeBody(r, obj)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		obj, err = h.Create(req, obj)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		WriteObject(w, r, http.StatusCreated, obj)
+	case "update":
+		obj := h.Resource().DeepCopyObject().(T)
+		err := DecodeBody(r, obj)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		obj, err = h.Update(req, obj)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		WriteObject(w, r, http.StatusOK, obj)
+	case "patch":
+		obj, err := h.patch(req)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		WriteObject(w, r, http.StatusOK, obj)
+	case "delete":
+		err := h.Delete(req)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		WriteStatus(w, r, &metav1.StatusDetails{
+			Name:  req.Info.Name,
+			Group: req.Info.APIGroup,
+			Kind:  h.kind(),
+		})
+	case "deletecollection":
+		err := h.deleteCollection(req)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		WriteStatus(w, r, &metav1.StatusDetails{
+			Group: req.Info.APIGroup,
+			Kind:  h.kind(),
+		})
+	case "watch":
+		watcher, err := h.Watch(req)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		WriteWatch(w, r, watcher)
+	default:
+		WriteError(w, r, methodNotSupported(req))
+	}
+}
+
+// patch applies the patch of the request to the object returned by Get and passes the
+// result to Update. Server-side apply is not supported, as it needs the field management
+// of the api server.
+func (h *resourceHandler[T]) patch(req *Request) (T, error) {
+	var empty T
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return empty, kerrors.NewBadRequest(fmt.Sprintf("parse content type: %v", err))
+	}
+	patchType := types.PatchType(mediaType)
+	supportedPatchTypes := []types.PatchType{types.JSONPatchType, types.MergePatchType}
+	if _, isUnstructured := any(h.Resource()).(runtime.Unstructured); !isUnstructured {
+		// strategic merge patches need the patch strategies of the typed struct
+		supportedPatchTypes = append(supportedPatchTypes, types.StrategicMergePatchType)
+	}
+	if !slices.Contains(supportedPatchTypes, patchType) {
+		return empty, unsupportedMediaType(fmt.Sprintf("patch type %s is not supported, supported types are %v", mediaType, supportedPatchTypes))
+	}
+
+	patch, err := io.ReadAll(req.Body)
+	if err != nil {
+		return empty, kerrors.NewBadRequest(fmt.Sprintf("read patch: %v", err))
+	}
+
+	current, err := h.Get(withVerb(req, "get"))
+	if err != nil {
+		return empty, err
+	}
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return empty, fmt.Errorf("encode %s: %w", req.Info.Name, err)
+	}
+
+	var patchedJSON []byte
+	switch patchType {
+	case types.JSONPatchType:
+		var jsonPatch jsonpatch.Patch
+		jsonPatch, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patchedJSON, err = jsonPatch.Apply(currentJSON)
+		}
+	case types.MergePatchType:
+		patchedJSON, err = jsonpatch.MergePatch(currentJSON, patch)
+	default:
+		patchedJSON, err = strategicpatch.StrategicMergePatch(currentJSON, patch, h.Resource())
+	}
+	if err != nil {
+		return empty, kerrors.NewBadRequest(fmt.Sprintf("apply patch: %v", err))
+	}
+
+	patched := h.Resource().DeepCopyObject().(T)
+	err = json.Unmarshal(patchedJSON, patched)
+	if err != nil {
+		return empty, kerrors.NewBadRequest(fmt.Sprintf("decode patched object: %v", err))
+	}
+
+	return h.Update(withVerb(req, "update"), patched)
+}
+
+// deleteCollection passes each object returned by List to Delete
+func (h *resourceHandler[T]) deleteCollection(req *Request) error {
+	list, err := h.List(withVerb(req, "list"))
+	if err != nil {
+		return err
+	}
+
+	return meta.EachListItem(list, func(obj runtime.Object) error {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+
+		deleteReq := withVerb(req, "delete")
+		deleteReq.Info.Namespace = accessor.GetNamespace()
+		deleteReq.Info.Name = accessor.GetName()
+		return h.Delete(deleteReq)
+	})
+}
+
+// kind returns the kind of the resource or an empty string if it is unknown
+func (h *resourceHandler[T]) kind() string {
+	gvk, err := apiutil.GVKForObject(h.Resource(), scheme.Scheme)
+	if err != nil {
+		return ""
+	}
+
+	return gvk.Kind
+}
+
+// withVerb returns a copy of the request with the given verb, which is passed to the
+// methods that serve a verb that is not supported directly
+func withVerb(req *Request, verb string) *Request {
+	info := *req.Info
+	info.Verb = verb
+	return &Request{
+		Request: req.Request,
+		Info:    &info,
+	}
+}
+
+func unsupportedMediaType(message string) error {
+	return &kerrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusUnsupportedMediaType,
+		Reason:  metav1.StatusReasonUnsupportedMediaType,
+		Message: message,
+	}}
+}
+
+func methodNotSupported(req *Request) error {
+	return kerrors.NewMethodNotSupported(schema.GroupResource{Group: req.Info.APIGroup, Resource: req.Info.Resource}, req.Info.Verb)
+}
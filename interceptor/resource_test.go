@@ -0,0 +1,264 @@
+package interceptor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	v2 "github.com/loft-sh/vcluster/pkg/plugin/v2"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestResourceInterceptorPatch(t *testing.T) {
+	testCases := []struct {
+		name         string
+		contentType  string
+		patch        string
+		expectedCode int
+		expected     map[string]string
+	}{
+		{
+			name:         "json patch",
+			contentType:  "application/json-patch+json",
+			patch:        `[{"op":"replace","path":"/data/a","value":"c"},{"op":"add","path":"/data/d","value":"e"}]`,
+			expectedCode: http.StatusOK,
+			expected:     map[string]string{"a": "c", "b": "b", "d": "e"},
+		},
+		{
+			name:         "merge patch",
+			contentType:  "application/merge-patch+json",
+			patch:        `{"data":{"a":"c","b":null}}`,
+			expectedCode: http.StatusOK,
+			expected:     map[string]string{"a": "c"},
+		},
+		{
+			name:         "strategic merge patch",
+			contentType:  "application/strategic-merge-patch+json",
+			patch:        `{"data":{"a":"c"}}`,
+			expectedCode: http.StatusOK,
+			expected:     map[string]string{"a": "c", "b": "b"},
+		},
+		{
+			name:         "server-side apply",
+			contentType:  "application/apply-patch+yaml",
+			patch:        `data: {a: c}`,
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "invalid json patch",
+			contentType:  "application/json-patch+json",
+			patch:        `[{"op":"replace","path":"/data/missing/a","value":"c"}]`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			configMaps := newConfigMapInterceptor()
+			r := httptest.NewRequest("PATCH", "/api/v1/namespaces/default/configmaps/a", strings.NewReader(testCase.patch))
+			r.Header.Set("Content-Type", testCase.contentType)
+
+			w := httptest.NewRecorder()
+			NewResourceInterceptor[*corev1.ConfigMap](configMaps).ServeHTTP(w, r)
+			if w.Code != testCase.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", testCase.expectedCode, w.Code, w.Body.String())
+			} else if testCase.expectedCode != http.StatusOK {
+				if configMaps.updated != nil {
+					t.Fatalf("expected no update, got %v", configMaps.updated)
+				}
+				return
+			}
+
+			if configMaps.updated == nil || configMaps.updated.Name != "a" || !reflect.DeepEqual(configMaps.updated.Data, testCase.expected) {
+				t.Fatalf("expected update of a with %v, got %v", testCase.expected, configMaps.updated)
+			}
+			response := &corev1.ConfigMap{}
+			err := json.Unmarshal(w.Body.Bytes(), response)
+			if err != nil {
+				t.Fatalf("decode response: %v", err)
+			} else if !reflect.DeepEqual(response.Data, testCase.expected) {
+				t.Fatalf("expected response with %v, got %v", testCase.expected, response.Data)
+			}
+		})
+	}
+}
+
+func TestResourceInterceptorDispatch(t *testing.T) {
+	testCases := []struct {
+		name            string
+		method          string
+		url             string
+		body            string
+		expectedCode    int
+		expectedDeleted []string
+		expectedStatus  *metav1.StatusDetails
+	}{
+		{
+			name:            "delete",
+			method:          "DELETE",
+			url:             "/api/v1/namespaces/default/configmaps/a",
+			expectedCode:    http.StatusOK,
+			expectedDeleted: []string{"default/a"},
+			expectedStatus:  &metav1.StatusDetails{Name: "a", Kind: "ConfigMap"},
+		},
+		{
+			name:            "delete collection",
+			method:          "DELETE",
+			url:             "/api/v1/namespaces/default/configmaps",
+			expectedCode:    http.StatusOK,
+			expectedDeleted: []string{"default/a", "default/b"},
+			expectedStatus:  &metav1.StatusDetails{Kind: "ConfigMap"},
+		},
+		{
+			name:            "delete collection with label selector",
+			method:          "DELETE",
+			url:             "/api/v1/namespaces/default/configmaps?labelSelector=app%3Dtest",
+			expectedCode:    http.StatusOK,
+			expectedDeleted: []string{"default/b"},
+			expectedStatus:  &metav1.StatusDetails{Kind: "ConfigMap"},
+		},
+		{
+			name:         "get",
+			method:       "GET",
+			url:          "/api/v1/namespaces/default/configmaps/a",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "get missing object",
+			method:       "GET",
+			url:          "/api/v1/namespaces/default/configmaps/missing",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "create",
+			method:       "POST",
+			url:          "/api/v1/namespaces/default/configmaps",
+			body:         `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"c"}}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "unimplemented watch",
+			method:       "GET",
+			url:          "/api/v1/namespaces/default/configmaps?watch=true",
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "non resource request",
+			method:       "GET",
+			url:          "/version",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			configMaps := newConfigMapInterceptor()
+			r := httptest.NewRequest(testCase.method, testCase.url, strings.NewReader(testCase.body))
+			if testCase.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+
+			w := httptest.NewRecorder()
+			NewResourceInterceptor[*corev1.ConfigMap](configMaps).ServeHTTP(w, r)
+			if w.Code != testCase.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", testCase.expectedCode, w.Code, w.Body.String())
+			}
+
+			sort.Strings(configMaps.deleted)
+			if strings.Join(configMaps.deleted, ",") != strings.Join(testCase.expectedDeleted, ",") {
+				t.Fatalf("expected deleted %v, got %v", testCase.expectedDeleted, configMaps.deleted)
+			}
+
+			if testCase.expectedStatus != nil {
+				status := &metav1.Status{}
+				err := json.Unmarshal(w.Body.Bytes(), status)
+				if err != nil {
+					t.Fatalf("decode status: %v", err)
+				} else if status.Status != metav1.StatusSuccess || !reflect.DeepEqual(status.Details, testCase.expectedStatus) {
+					t.Fatalf("expected success status with %v, got %v", testCase.expectedStatus, status)
+				}
+			}
+		})
+	}
+}
+
+// configMapInterceptor serves the config maps a and b of the default namespace and records
+// updates and deletions
+type configMapInterceptor struct {
+	UnimplementedResourceInterceptor[*corev1.ConfigMap]
+
+	objs    []*corev1.ConfigMap
+	updated *corev1.ConfigMap
+	deleted []string
+}
+
+func newConfigMapInterceptor() *configMapInterceptor {
+	return &configMapInterceptor{objs: []*corev1.ConfigMap{
+		{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}, Data: map[string]string{"a": "a", "b": "b"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default", Labels: map[string]string{"app": "test"}}},
+	}}
+}
+
+func (c *configMapInterceptor) Name() string {
+	return "configmaps"
+}
+
+func (c *configMapInterceptor) Resource() *corev1.ConfigMap {
+	return &corev1.ConfigMap{}
+}
+
+func (c *configMapInterceptor) InterceptionRules() []v2.InterceptorRule {
+	return []v2.InterceptorRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"*"}}}
+}
+
+func (c *configMapInterceptor) Get(req *Request) (*corev1.ConfigMap, error) {
+	for _, obj := range c.objs {
+		if obj.Namespace == req.Info.Namespace && obj.Name == req.Info.Name {
+			return obj.DeepCopy(), nil
+		}
+	}
+
+	return nil, kerrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, req.Info.Name)
+}
+
+func (c *configMapInterceptor) List(req *Request) (client.ObjectList, error) {
+	listOptions, err := DecodeListOptions(req.Request)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := newObjectSelector(req.Info.Namespace, listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &corev1.ConfigMapList{}
+	for _, obj := range c.objs {
+		if selector.matches(obj) {
+			list.Items = append(list.Items, *obj.DeepCopy())
+		}
+	}
+
+	return list, nil
+}
+
+func (c *configMapInterceptor) Create(_ *Request, obj *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	return obj, nil
+}
+
+func (c *configMapInterceptor) Update(_ *Request, obj *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	c.updated = obj
+	return obj, nil
+}
+
+func (c *configMapInterceptor) Delete(req *Request) error {
+	c.deleted = append(c.deleted, req.Info.Namespace+"/"+req.Info.Name)
+	return nil
+}
@@ -0,0 +1,64 @@
+package interceptor
+
+import (
+	"net/http"
+
+	"github.com/loft-sh/vcluster/pkg/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// WriteObject writes the object in the format the client negotiated, e.g. json, yaml
+// or protobuf.
+func WriteObject(w http.ResponseWriter, r *http.Request, statusCode int, obj runtime.Object) {
+	responsewriters.WriteObjectNegotiated(
+		Codecs,
+		negotiation.DefaultEndpointRestrictions,
+		groupVersionFor(r, obj),
+		w,
+		r,
+		statusCode,
+		obj,
+		false,
+	)
+}
+
+// WriteError writes the error as metav1.Status in the format the client negotiated.
+// Errors that are not kubernetes api errors are returned as internal errors.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	responsewriters.ErrorNegotiated(err, Codecs, groupVersionFor(r, nil), w, r)
+}
+
+// WriteStatus writes a successful metav1.Status, as returned by the api server for deletions.
+// The kind of the details is the kind of the deleted object, e.g. Pod, not the resource.
+func WriteStatus(w http.ResponseWriter, r *http.Request, details *metav1.StatusDetails) {
+	WriteObject(w, r, http.StatusOK, &metav1.Status{
+		Status:  metav1.StatusSuccess,
+		Code:    http.StatusOK,
+		Details: details,
+	})
+}
+
+// groupVersionFor returns the group version the object is encoded in. It will fall back
+// to the group version of the request for unknown or unset objects.
+func groupVersionFor(r *http.Request, obj runtime.Object) schema.GroupVersion {
+	if obj != nil {
+		if _, ok := obj.(*metav1.Status); !ok {
+			gvk, err := apiutil.GVKForObject(obj, scheme.Scheme)
+			if err == nil {
+				return gvk.GroupVersion()
+			}
+		}
+	}
+
+	info, err := RequestInfo(r)
+	if err != nil || !info.IsResourceRequest {
+		return metav1.Unversioned
+	}
+
+	return schema.GroupVersion{Group: info.APIGroup, Version: info.APIVersion}
+}
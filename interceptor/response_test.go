@@ -0,0 +1,145 @@
+package interceptor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWriteObject(t *testing.T) {
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}, Data: map[string]string{"a": "b"}}
+
+	testCases := []struct {
+		name                string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "json by default",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody:        `"kind":"ConfigMap","apiVersion":"v1"`,
+		},
+		{
+			name:                "yaml",
+			accept:              "application/yaml",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/yaml",
+			expectedBody:        "apiVersion: v1\n",
+		},
+		{
+			name:                "protobuf",
+			accept:              "application/vnd.kubernetes.protobuf",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/vnd.kubernetes.protobuf",
+			expectedBody:        "ConfigMap",
+		},
+		{
+			name:                "first acceptable type",
+			accept:              "text/html, application/yaml",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/yaml",
+		},
+		{
+			name:         "not acceptable",
+			accept:       "text/html",
+			expectedCode: http.StatusNotAcceptable,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/namespaces/default/configmaps/test", nil)
+			if testCase.accept != "" {
+				r.Header.Set("Accept", testCase.accept)
+			}
+
+			w := httptest.NewRecorder()
+			WriteObject(w, r, http.StatusOK, configMap.DeepCopy())
+			if w.Code != testCase.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", testCase.expectedCode, w.Code, w.Body.String())
+			} else if testCase.expectedContentType != "" && w.Header().Get("Content-Type") != testCase.expectedContentType {
+				t.Fatalf("expected content type %s, got %s", testCase.expectedContentType, w.Header().Get("Content-Type"))
+			} else if !strings.Contains(w.Body.String(), testCase.expectedBody) {
+				t.Fatalf("expected body to contain %q, got %q", testCase.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestWriteStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		write    func(w http.ResponseWriter, r *http.Request)
+		expected metav1.Status
+	}{
+		{
+			name: "success",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				WriteStatus(w, r, &metav1.StatusDetails{Name: "test", Kind: "ConfigMap"})
+			},
+			expected: metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusSuccess,
+				Code:     http.StatusOK,
+				Details:  &metav1.StatusDetails{Name: "test", Kind: "ConfigMap"},
+			},
+		},
+		{
+			name: "api error",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				WriteError(w, r, kerrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "test"))
+			},
+			expected: metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Message:  `configmaps "test" not found`,
+				Reason:   metav1.StatusReasonNotFound,
+				Code:     http.StatusNotFound,
+				Details:  &metav1.StatusDetails{Name: "test", Kind: "configmaps"},
+			},
+		},
+		{
+			name: "other error",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				WriteError(w, r, http.ErrBodyNotAllowed)
+			},
+			expected: metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Message:  http.ErrBodyNotAllowed.Error(),
+				Code:     http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			testCase.write(w, httptest.NewRequest("DELETE", "/api/v1/namespaces/default/configmaps/test", nil))
+			if w.Code != int(testCase.expected.Code) {
+				t.Fatalf("expected status %d, got %d", testCase.expected.Code, w.Code)
+			}
+
+			status := metav1.Status{}
+			err := json.Unmarshal(w.Body.Bytes(), &status)
+			if err != nil {
+				t.Fatalf("decode status: %v", err)
+			}
+			expectedJSON, _ := json.Marshal(testCase.expected)
+			actualJSON, _ := json.Marshal(status)
+			if string(expectedJSON) != string(actualJSON) {
+				t.Fatalf("expected %s, got %s", expectedJSON, actualJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package interceptor
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/loft-sh/vcluster-sdk/plugin"
+	v2 "github.com/loft-sh/vcluster/pkg/plugin/v2"
+	"github.com/loft-sh/vcluster/pkg/scheme"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ViewOptions configure a read-only view of host resources
+type ViewOptions struct {
+	// Resource is the plural resource name the view is served for, e.g. certificates.
+	// Defaults to the resource the host cluster maps the kind to.
+	Resource string
+
+	// LabelSelector restricts the host objects that are visible in the virtual cluster
+	LabelSelector labels.Selector
+}
+
+// NewHostView creates an interceptor that serves get, list and watch requests for the kind
+// of the mapper from the host informer cache, without copying the objects into the virtual
+// cluster. Host objects are only visible if the mapper translates them to a virtual name,
+// e.g. objects of a shared platform namespace. All other requests are rejected with
+// forbidden. The view intercepts all requests for its resource, so the kind and resource
+// must not be served by the virtual cluster, e.g. custom resources whose definition only
+// exists in the host cluster. The host manager cache has to include the namespaces of the
+// viewed objects, see Options.ModifyHostManager.
+func NewHostView[T client.Object](ctx *synccontext.RegisterContext, name string, mapper synccontext.Mapper, options ViewOptions) (plugin.Interceptor, error) {
+	gvk := mapper.GroupVersionKind()
+	obj, err := scheme.Scheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("create object for %s: %w", gvk.String(), err)
+	}
+	typedObj, ok := obj.(T)
+	if !ok {
+		return nil, fmt.Errorf("kind %s of mapper %T does not match the view type %T", gvk.String(), mapper, *new(T))
+	}
+
+	resource := options.Resource
+	if resource == "" {
+		mapping, err := ctx.HostManager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, fmt.Errorf("find resource for %s: %w", gvk.String(), err)
+		}
+
+		resource = mapping.Resource.Resource
+	}
+	err = checkViewResource(ctx.VirtualManager.GetRESTMapper(), gvk, gvk.GroupVersion().WithResource(resource))
+	if err != nil {
+		return nil, err
+	}
+
+	informer, err := ctx.HostManager.GetCache().GetInformer(ctx, typedObj)
+	if err != nil {
+		return nil, fmt.Errorf("get host informer for %s: %w", gvk.String(), err)
+	}
+
+	labelSelector := options.LabelSelector
+	if labelSelector == nil {
+		labelSelector = labels.Everything()
+	}
+
+	view := &hostView[T]{
+		name:     name,
+		gvk:      gvk,
+		resource: schema.GroupResource{Group: gvk.Group, Resource: resource},
+		obj:      typedObj,
+
+		syncContext:   ctx.ToSyncContext(name),
+		hostCache:     ctx.HostManager.GetCache(),
+		informer:      informer,
+		mapper:        mapper,
+		labelSelector: labelSelector,
+	}
+	view.handler = NewResourceInterceptor[T](view)
+	return view, nil
+}
+
+// checkViewResource makes sure the virtual cluster serves neither the kind nor the resource
+// of a view, as the view would hide the virtual objects and reject writes to them
+func checkViewResource(virtualMapper meta.RESTMapper, gvk schema.GroupVersionKind, gvr schema.GroupVersionResource) error {
+	_, err := virtualMapper.RESTMapping(gvk.GroupKind())
+	if err == nil {
+		return fmt.Errorf("kind %s is served by the virtual cluster, a view would hide its objects", gvk.GroupKind().String())
+	} else if !meta.IsNoMatchError(err) {
+		return fmt.Errorf("find %s in the virtual cluster: %w", gvk.GroupKind().String(), err)
+	}
+
+	_, err = virtualMapper.KindsFor(gvr.GroupResource().WithVersion(""))
+	if err == nil {
+		return fmt.Errorf("resource %s is served by the virtual cluster, a view would hide its objects", gvr.GroupResource().String())
+	} else if !meta.IsNoMatchError(err) {
+		return fmt.Errorf("find %s in the virtual cluster: %w", gvr.GroupResource().String(), err)
+	}
+
+	return nil
+}
+
+type hostView[T client.Object] struct {
+	UnimplementedResourceInterceptor[T]
+
+	name     string
+	gvk      schema.GroupVersionKind
+	resource schema.GroupResource
+	obj      T
+
+	syncContext   *synccontext.SyncContext
+	hostCache     cache.Cache
+	informer      cache.Informer
+	mapper        synccontext.Mapper
+	labelSelector labels.Selector
+
+	handler http.Handler
+}
+
+func (v *hostView[T]) Name() string {
+	return v.name
+}
+
+func (v *hostView[T]) Resource() T {
+	return v.obj
+}
+
+func (v *hostView[T]) InterceptionRules() []v2.InterceptorRule {
+	return []v2.InterceptorRule{
+		{
+			APIGroups: []string{v.resource.Group},
+			Resources: []string{v.resource.Resource},
+			Verbs:     []string{"*"},
+		},
+	}
+}
+
+func (v *hostView[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	info, err := RequestInfo(r)
+	if err != nil {
+		WriteError(w, r, kerrors.NewBadRequest(err.Error()))
+		return
+	}
+
+	switch info.Verb {
+	case "get", "list", "watch":
+		if info.Subresource != "" {
+			WriteError(w, r, kerrors.NewForbidden(v.resource, info.Name, fmt.Errorf("subresource %s is not available for host resources", info.Subresource)))
+			return
+		}
+
+		v.handler.ServeHTTP(w, r)
+	default:
+		WriteError(w, r, kerrors.NewForbidden(v.resource, info.Name, fmt.Errorf("host resources are read-only in the virtual cluster")))
+	}
+}
+
+func (v *hostView[T]) Get(req *Request) (T, error) {
+	var empty T
+
+	virtualName := types.NamespacedName{Namespace: req.Info.Namespace, Name: req.Info.Name}
+	hostName := v.mapper.VirtualToHost(v.syncContext, virtualName, nil)
+	if hostName.Name == "" {
+		return empty, kerrors.NewNotFound(v.resource, req.Info.Name)
+	}
+
+	hostObj := v.obj.DeepCopyObject().(T)
+	err := v.hostCache.Get(req.Context(), hostName, hostObj)
+	if kerrors.IsNotFound(err) {
+		return empty, kerrors.NewNotFound(v.resource, req.Info.Name)
+	} else if err != nil {
+		return empty, err
+	}
+
+	// make sure the object is actually part of the view
+	virtualObj, ok := v.toVirtual(hostObj)
+	if !ok || virtualObj.GetName() != virtualName.Name || virtualObj.GetNamespace() != virtualName.Namespace {
+		return empty, kerrors.NewNotFound(v.resource, req.Info.Name)
+	}
+
+	return virtualObj, nil
+}
+
+func (v *hostView[T]) List(req *Request) (client.ObjectList, error) {
+	listOptions, err := DecodeListOptions(req.Request)
+	if err != nil {
+		return nil, err
+	}
+	// names and namespaces differ between host and virtual cluster, so the selectors
+	// are matched against the translated objects
+	selector, err := newObjectSelector(req.Info.Namespace, listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	listObj, err := scheme.Scheme.New(v.gvk.GroupVersion().WithKind(v.gvk.Kind + "List"))
+	if err != nil {
+		return nil, fmt.Errorf("create list for %s: %w", v.gvk.String(), err)
+	}
+	hostList, ok := listObj.(client.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("list of %s is not a client.ObjectList", v.gvk.String())
+	}
+
+	err = v.hostCache.List(req.Context(), hostList)
+	if err != nil {
+		return nil, err
+	}
+	hostObjs, err := meta.ExtractList(hostList)
+	if err != nil {
+		return nil, err
+	}
+
+	virtualObjs := []runtime.Object{}
+	for _, hostObj := range hostObjs {
+		virtualObj, ok := v.toVirtual(hostObj.(client.Object))
+		if !ok || !selector.matches(virtualObj) {
+			continue
+		}
+
+		virtualObjs = append(virtualObjs, virtualObj)
+	}
+
+	err = meta.SetList(hostList, virtualObjs)
+	if err != nil {
+		return nil, err
+	}
+
+	// clients such as informers watch from the resource version of the list, so the
+	// watch does not send the listed objects again
+	if informer, ok := v.informer.(interface{ LastSyncResourceVersion() string }); ok {
+		hostList.SetResourceVersion(informer.LastSyncResourceVersion())
+	}
+
+	return hostList, nil
+}
+
+func (v *hostView[T]) Watch(req *Request) (watch.Interface, error) {
+	listOptions, err := DecodeListOptions(req.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	// names and namespaces differ between host and virtual cluster, so the selectors
+	// are matched against the translated objects
+	return newInformerWatcher(v.informer, req.Info.Namespace, listOptions, func(hostObj client.Object) (client.Object, bool) {
+		return v.toVirtual(hostObj)
+	})
+}
+
+// toVirtual translates the host object into its virtual representation. It returns false
+// if the object is not part of the view.
+func (v *hostView[T]) toVirtual(hostObj client.Object) (T, bool) {
+	var empty T
+	if !v.labelSelector.Matches(labels.Set(hostObj.GetLabels())) {
+		return empty, false
+	}
+
+	virtualName := v.mapper.HostToVirtual(v.syncContext, types.NamespacedName{Namespace: hostObj.GetNamespace(), Name: hostObj.GetName()}, hostObj)
+	if virtualName.Name == "" {
+		return empty, false
+	}
+
+	virtualObj, ok := hostObj.DeepCopyObject().(T)
+	if !ok {
+		return empty, false
+	}
+	virtualObj.SetName(virtualName.Name)
+	virtualObj.SetNamespace(virtualName.Namespace)
+	virtualObj.SetOwnerReferences(nil)
+	virtualObj.SetManagedFields(nil)
+	virtualObj.GetObjectKind().SetGroupVersionKind(v.gvk)
+	return virtualObj, true
+}
@@ -0,0 +1,356 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var configMapGVK = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+func TestHostViewToVirtual(t *testing.T) {
+	testCases := []struct {
+		name          string
+		hostObj       *corev1.ConfigMap
+		labelSelector labels.Selector
+		expected      *types.NamespacedName
+	}{
+		{
+			name:     "mapped object",
+			hostObj:  newHostConfigMap("platform", "shared-a", nil),
+			expected: &types.NamespacedName{Namespace: "default", Name: "a"},
+		},
+		{
+			name:    "unmapped object",
+			hostObj: newHostConfigMap("other", "c", nil),
+		},
+		{
+			name:          "label selector matches",
+			hostObj:       newHostConfigMap("platform", "shared-a", map[string]string{"shared": "true"}),
+			labelSelector: labels.SelectorFromSet(labels.Set{"shared": "true"}),
+			expected:      &types.NamespacedName{Namespace: "default", Name: "a"},
+		},
+		{
+			name:          "label selector does not match",
+			hostObj:       newHostConfigMap("platform", "shared-a", nil),
+			labelSelector: labels.SelectorFromSet(labels.Set{"shared": "true"}),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			view := newTestHostView(testCase.labelSelector)
+			virtualObj, ok := view.toVirtual(testCase.hostObj)
+			if testCase.expected == nil {
+				if ok {
+					t.Fatalf("expected object to be filtered, got %s/%s", virtualObj.Namespace, virtualObj.Name)
+				}
+				return
+			} else if !ok {
+				t.Fatalf("expected object to be part of the view")
+			}
+
+			if virtualObj.Namespace != testCase.expected.Namespace || virtualObj.Name != testCase.expected.Name {
+				t.Fatalf("expected %s, got %s/%s", testCase.expected.String(), virtualObj.Namespace, virtualObj.Name)
+			} else if len(virtualObj.OwnerReferences) != 0 || len(virtualObj.ManagedFields) != 0 {
+				t.Fatalf("expected owner references and managed fields to be removed")
+			} else if virtualObj.GroupVersionKind() != configMapGVK {
+				t.Fatalf("expected kind %s, got %s", configMapGVK.String(), virtualObj.GroupVersionKind().String())
+			} else if testCase.hostObj.Namespace != "platform" {
+				t.Fatalf("expected host object to stay unchanged")
+			}
+		})
+	}
+}
+
+func TestHostViewGet(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{
+			name:     "mapped object",
+			url:      "/api/v1/namespaces/default/configmaps/a",
+			expected: "default/a",
+		},
+		{
+			name: "unmapped name",
+			url:  "/api/v1/namespaces/default/configmaps/c",
+		},
+		{
+			name: "mapped name of a missing host object",
+			url:  "/api/v1/namespaces/default/configmaps/missing",
+		},
+		{
+			name: "host object maps back to another name",
+			url:  "/api/v1/namespaces/default/configmaps/alias-old",
+		},
+		{
+			name: "host object filtered by label selector",
+			url:  "/api/v1/namespaces/default/configmaps/b",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			view := newTestHostView(labels.SelectorFromSet(labels.Set{"shared": "true"}))
+			req, err := NewRequest(httptest.NewRequest("GET", testCase.url, nil))
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+
+			obj, err := view.Get(req)
+			if testCase.expected == "" {
+				if !kerrors.IsNotFound(err) {
+					t.Fatalf("expected not found, got %v", err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if obj.Namespace+"/"+obj.Name != testCase.expected {
+				t.Fatalf("expected %s, got %s/%s", testCase.expected, obj.Namespace, obj.Name)
+			}
+		})
+	}
+}
+
+func TestHostViewList(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		expected []string
+		err      string
+	}{
+		{
+			name:     "all namespaces",
+			url:      "/api/v1/configmaps",
+			expected: []string{"default/a", "default/alias", "kube-public/d"},
+		},
+		{
+			name:     "namespace",
+			url:      "/api/v1/namespaces/default/configmaps",
+			expected: []string{"default/a", "default/alias"},
+		},
+		{
+			name:     "virtual name field selector",
+			url:      "/api/v1/configmaps?fieldSelector=metadata.name%3Da",
+			expected: []string{"default/a"},
+		},
+		{
+			name:     "host name field selector",
+			url:      "/api/v1/configmaps?fieldSelector=metadata.name%3Dshared-a",
+			expected: []string{},
+		},
+		{
+			name:     "virtual namespace field selector",
+			url:      "/api/v1/configmaps?fieldSelector=metadata.namespace%3Dkube-public",
+			expected: []string{"kube-public/d"},
+		},
+		{
+			name:     "label selector",
+			url:      "/api/v1/configmaps?labelSelector=team%3Dblue",
+			expected: []string{"default/alias"},
+		},
+		{
+			name: "invalid label selector",
+			url:  "/api/v1/configmaps?labelSelector=team%3D%3D%3D",
+			err:  "parse label selector",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			view := newTestHostView(labels.SelectorFromSet(labels.Set{"shared": "true"}))
+			req, err := NewRequest(httptest.NewRequest("GET", testCase.url, nil))
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+
+			list, err := view.List(req)
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			objs, err := meta.ExtractList(list)
+			if err != nil {
+				t.Fatalf("extract list: %v", err)
+			}
+			names := []string{}
+			for _, obj := range objs {
+				names = append(names, objectKeyOf(obj.(client.Object)))
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, testCase.expected) {
+				t.Fatalf("expected %v, got %v", testCase.expected, names)
+			}
+		})
+	}
+}
+
+func TestCheckViewResource(t *testing.T) {
+	virtualMapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Version: "v1"}})
+	virtualMapper.Add(configMapGVK, meta.RESTScopeNamespace)
+
+	testCases := []struct {
+		name string
+		gvk  schema.GroupVersionKind
+		gvr  schema.GroupVersionResource
+		err  string
+	}{
+		{
+			name: "host only kind",
+			gvk:  schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"},
+			gvr:  schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"},
+		},
+		{
+			name: "kind served by the virtual cluster",
+			gvk:  configMapGVK,
+			gvr:  schema.GroupVersionResource{Version: "v1", Resource: "hostconfigmaps"},
+			err:  "kind ConfigMap is served",
+		},
+		{
+			name: "resource served by the virtual cluster",
+			gvk:  schema.GroupVersionKind{Version: "v1", Kind: "HostConfigMap"},
+			gvr:  schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			err:  "resource configmaps is served",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := checkViewResource(virtualMapper, testCase.gvk, testCase.gvr)
+			if testCase.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), testCase.err) {
+				t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+			}
+		})
+	}
+}
+
+func newTestHostView(labelSelector labels.Selector) *hostView[*corev1.ConfigMap] {
+	if labelSelector == nil {
+		labelSelector = labels.Everything()
+	}
+
+	shared := map[string]string{"shared": "true"}
+	return &hostView[*corev1.ConfigMap]{
+		gvk:      configMapGVK,
+		resource: schema.GroupResource{Resource: "configmaps"},
+		obj:      &corev1.ConfigMap{},
+
+		syncContext: &synccontext.SyncContext{},
+		hostCache: &objectsCache{objs: []*corev1.ConfigMap{
+			newHostConfigMap("platform", "shared-a", shared),
+			newHostConfigMap("platform", "shared-b", nil),
+			newHostConfigMap("platform", "shared-alias", map[string]string{"shared": "true", "team": "blue"}),
+			newHostConfigMap("platform", "shared-d", shared),
+			newHostConfigMap("other", "c", shared),
+		}},
+		mapper: &viewMapper{
+			virtualToHost: map[types.NamespacedName]types.NamespacedName{
+				{Namespace: "default", Name: "a"}:       {Namespace: "platform", Name: "shared-a"},
+				{Namespace: "default", Name: "b"}:       {Namespace: "platform", Name: "shared-b"},
+				{Namespace: "default", Name: "missing"}: {Namespace: "platform", Name: "shared-missing"},
+				// the host object of alias maps back to default/alias, not to default/alias-old
+				{Namespace: "default", Name: "alias-old"}: {Namespace: "platform", Name: "shared-alias"},
+				{Namespace: "default", Name: "alias"}:     {Namespace: "platform", Name: "shared-alias"},
+				{Namespace: "kube-public", Name: "d"}:     {Namespace: "platform", Name: "shared-d"},
+			},
+			hostToVirtual: map[types.NamespacedName]types.NamespacedName{
+				{Namespace: "platform", Name: "shared-a"}:     {Namespace: "default", Name: "a"},
+				{Namespace: "platform", Name: "shared-b"}:     {Namespace: "default", Name: "b"},
+				{Namespace: "platform", Name: "shared-alias"}: {Namespace: "default", Name: "alias"},
+				{Namespace: "platform", Name: "shared-d"}:     {Namespace: "kube-public", Name: "d"},
+			},
+		},
+		labelSelector: labelSelector,
+	}
+}
+
+func newHostConfigMap(namespace, name string, labels map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       namespace,
+		Name:            name,
+		Labels:          labels,
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: "owner"}},
+		ManagedFields:   []metav1.ManagedFieldsEntry{{Manager: "test"}},
+	}}
+}
+
+func objectKeyOf(obj client.Object) string {
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// viewMapper translates the names in its maps and returns an empty name for all others
+type viewMapper struct {
+	synccontext.Mapper
+
+	virtualToHost map[types.NamespacedName]types.NamespacedName
+	hostToVirtual map[types.NamespacedName]types.NamespacedName
+}
+
+func (m *viewMapper) VirtualToHost(_ *synccontext.SyncContext, req types.NamespacedName, _ client.Object) types.NamespacedName {
+	return m.virtualToHost[req]
+}
+
+func (m *viewMapper) HostToVirtual(_ *synccontext.SyncContext, req types.NamespacedName, _ client.Object) types.NamespacedName {
+	return m.hostToVirtual[req]
+}
+
+// objectsCache serves gets and lists of config maps from objs
+type objectsCache struct {
+	cache.Cache
+
+	objs []*corev1.ConfigMap
+}
+
+func (c *objectsCache) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	for _, existing := range c.objs {
+		if client.ObjectKeyFromObject(existing) == key {
+			existing.DeepCopyInto(obj.(*corev1.ConfigMap))
+			return nil
+		}
+	}
+
+	return kerrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, key.Name)
+}
+
+func (c *objectsCache) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	if _, ok := list.(*corev1.ConfigMapList); !ok {
+		return fmt.Errorf("unexpected list %T", list)
+	}
+
+	objs := []runtime.Object{}
+	for _, obj := range c.objs {
+		objs = append(objs, obj.DeepCopy())
+	}
+
+	return meta.SetList(list, objs)
+}
@@ -0,0 +1,343 @@
+package interceptor
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// BookmarkInterval is the interval in which watches created by NewInformerWatcher send
+// bookmark events, if the client allowed them.
+var BookmarkInterval = time.Minute
+
+// WriteWatch streams the events of the watcher to the client as kubernetes watch response
+// in the format the client negotiated. It blocks until the watcher is closed, the client
+// disconnects or the timeout of the request is reached and stops the watcher afterwards.
+// To stream events from a channel, wrap it with watch.NewProxyWatcher.
+func WriteWatch(w http.ResponseWriter, r *http.Request, watcher watch.Interface) {
+	defer watcher.Stop()
+
+	serializerInfo, err := negotiation.NegotiateOutputMediaTypeStream(r, Codecs, negotiation.DefaultEndpointRestrictions)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	listOptions, err := DecodeListOptions(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, r, kerrors.NewInternalError(fmt.Errorf("unable to start watch, response writer does not support flushing")))
+		return
+	}
+
+	framer := serializerInfo.StreamSerializer.Framer.NewFrameWriter(w)
+	// like the apiserver, only the embedded object is encoded for the group version of the
+	// request, the watch event itself is encoded unversioned, so this also works for group
+	// versions that are not registered in the scheme
+	encoder := serializerInfo.StreamSerializer.Serializer
+	embeddedEncoder := Codecs.EncoderForVersion(serializerInfo.Serializer, groupVersionFor(r, nil))
+	mediaType := serializerInfo.MediaType
+	if mediaType != runtime.ContentTypeJSON {
+		mediaType += ";stream=watch"
+	}
+
+	var timeout <-chan time.Time
+	if listOptions.TimeoutSeconds != nil {
+		timer := time.NewTimer(time.Duration(*listOptions.TimeoutSeconds) * time.Second)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	// begin the stream
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Transfer-Encoding", "chunked")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	resultChan := watcher.ResultChan()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timeout:
+			return
+		case event, ok := <-resultChan:
+			if !ok {
+				return
+			} else if event.Type == watch.Bookmark && !listOptions.AllowWatchBookmarks {
+				continue
+			}
+
+			objectBuffer := &bytes.Buffer{}
+			err := embeddedEncoder.Encode(event.Object, objectBuffer)
+			if err != nil {
+				klog.FromContext(r.Context()).Error(err, "error encoding watch object")
+				return
+			}
+
+			eventBuffer := &bytes.Buffer{}
+			err = encoder.Encode(&metav1.WatchEvent{
+				Type:   string(event.Type),
+				Object: runtime.RawExtension{Raw: objectBuffer.Bytes()},
+			}, eventBuffer)
+			if err != nil {
+				klog.FromContext(r.Context()).Error(err, "error encoding watch event")
+				return
+			}
+
+			_, err = framer.Write(eventBuffer.Bytes())
+			if err != nil {
+				// client disconnected
+				return
+			}
+
+			if len(resultChan) == 0 {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// NewInformerWatcher creates a watch for the objects of the given informer that are in the
+// namespace and match the label and field selectors of the list options. Only the
+// metadata.name and metadata.namespace field selectors are supported. If the list options
+// do not specify a resource version, the watch starts with an added event for each
+// existing object.
+func NewInformerWatcher(informer cache.Informer, namespace string, listOptions *metav1.ListOptions) (watch.Interface, error) {
+	return newInformerWatcher(informer, namespace, listOptions, nil)
+}
+
+// newInformerWatcher creates an informer watch that passes the objects through transform
+// before matching them, if set. Objects transform returns false for are ignored.
+func newInformerWatcher(informer cache.Informer, namespace string, listOptions *metav1.ListOptions, transform func(obj client.Object) (client.Object, bool)) (watch.Interface, error) {
+	selector, err := newObjectSelector(namespace, listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher := &informerWatcher{
+		selector:    selector,
+		sendInitial: listOptions.ResourceVersion == "" || listOptions.ResourceVersion == "0",
+		transform:   transform,
+
+		result: make(chan watch.Event, 100),
+		stopCh: make(chan struct{}),
+	}
+
+	watcher.registration, err = informer.AddEventHandler(toolscache.ResourceEventHandlerDetailedFuncs{
+		AddFunc:    watcher.onAdd,
+		UpdateFunc: watcher.onUpdate,
+		DeleteFunc: watcher.onDelete,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("add event handler: %w", err)
+	}
+	watcher.informer = informer
+
+	if listOptions.AllowWatchBookmarks {
+		go watcher.sendBookmarks()
+	}
+
+	return watcher, nil
+}
+
+type informerWatcher struct {
+	informer     cache.Informer
+	registration toolscache.ResourceEventHandlerRegistration
+
+	selector    *objectSelector
+	sendInitial bool
+	transform   func(obj client.Object) (client.Object, bool)
+
+	// m guards result against being closed while sending
+	m       sync.RWMutex
+	result  chan watch.Event
+	stopped bool
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+
+	lastObjectMutex sync.Mutex
+	lastObject      client.Object
+}
+
+func (i *informerWatcher) ResultChan() <-chan watch.Event {
+	return i.result
+}
+
+func (i *informerWatcher) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.stopCh)
+		if i.informer != nil {
+			_ = i.informer.RemoveEventHandler(i.registration)
+		}
+
+		i.m.Lock()
+		defer i.m.Unlock()
+
+		i.stopped = true
+		close(i.result)
+	})
+}
+
+func (i *informerWatcher) onAdd(obj interface{}, isInInitialList bool) {
+	if isInInitialList && !i.sendInitial {
+		return
+	}
+
+	clientObj, ok := i.convert(obj)
+	if !ok || !i.matches(clientObj) {
+		return
+	}
+
+	i.send(watch.Added, clientObj)
+}
+
+func (i *informerWatcher) onUpdate(oldObj, newObj interface{}) {
+	oldClientObj, oldOk := i.convert(oldObj)
+	newClientObj, newOk := i.convert(newObj)
+
+	oldMatches, newMatches := oldOk && i.matches(oldClientObj), newOk && i.matches(newClientObj)
+	switch {
+	case oldMatches && newMatches:
+		i.send(watch.Modified, newClientObj)
+	case oldMatches && newOk:
+		i.send(watch.Deleted, newClientObj)
+	case oldMatches:
+		i.send(watch.Deleted, oldClientObj)
+	case newMatches:
+		i.send(watch.Added, newClientObj)
+	}
+}
+
+func (i *informerWatcher) onDelete(obj interface{}) {
+	if deletedFinalStateUnknown, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = deletedFinalStateUnknown.Obj
+	}
+
+	clientObj, ok := i.convert(obj)
+	if !ok || !i.matches(clientObj) {
+		return
+	}
+
+	i.send(watch.Deleted, clientObj)
+}
+
+func (i *informerWatcher) convert(obj interface{}) (client.Object, bool) {
+	clientObj, ok := obj.(client.Object)
+	if !ok || i.transform == nil {
+		return clientObj, ok
+	}
+
+	return i.transform(clientObj)
+}
+
+func (i *informerWatcher) matches(obj client.Object) bool {
+	return i.selector.matches(obj)
+}
+
+func (i *informerWatcher) send(eventType watch.EventType, obj client.Object) {
+	i.lastObjectMutex.Lock()
+	i.lastObject = obj
+	i.lastObjectMutex.Unlock()
+
+	i.m.RLock()
+	defer i.m.RUnlock()
+	if i.stopped {
+		return
+	}
+
+	select {
+	case i.result <- watch.Event{Type: eventType, Object: obj.DeepCopyObject()}:
+	case <-i.stopCh:
+	}
+}
+
+// sendBookmarks periodically sends a bookmark with the latest resource version seen
+func (i *informerWatcher) sendBookmarks() {
+	ticker := time.NewTicker(BookmarkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.stopCh:
+			return
+		case <-ticker.C:
+			i.lastObjectMutex.Lock()
+			lastObject := i.lastObject
+			i.lastObjectMutex.Unlock()
+			if lastObject == nil {
+				continue
+			}
+
+			// bookmarks only carry the resource version in an otherwise empty object
+			bookmark := reflect.New(reflect.TypeOf(lastObject).Elem()).Interface().(client.Object)
+			bookmark.SetResourceVersion(lastObject.GetResourceVersion())
+			bookmark.GetObjectKind().SetGroupVersionKind(lastObject.GetObjectKind().GroupVersionKind())
+
+			i.m.RLock()
+			if !i.stopped {
+				select {
+				case i.result <- watch.Event{Type: watch.Bookmark, Object: bookmark}:
+				case <-i.stopCh:
+				}
+			}
+			i.m.RUnlock()
+		}
+	}
+}
+
+// objectSelector matches objects against the namespace and the label and field selectors
+// of a list or watch request. Only the metadata.name and metadata.namespace field
+// selectors are supported.
+type objectSelector struct {
+	namespace     string
+	labelSelector labels.Selector
+	fieldSelector fields.Selector
+}
+
+func newObjectSelector(namespace string, listOptions *metav1.ListOptions) (*objectSelector, error) {
+	labelSelector, err := labels.Parse(listOptions.LabelSelector)
+	if err != nil {
+		return nil, kerrors.NewBadRequest(fmt.Sprintf("parse label selector: %v", err))
+	}
+	fieldSelector, err := fields.ParseSelector(listOptions.FieldSelector)
+	if err != nil {
+		return nil, kerrors.NewBadRequest(fmt.Sprintf("parse field selector: %v", err))
+	}
+
+	return &objectSelector{
+		namespace:     namespace,
+		labelSelector: labelSelector,
+		fieldSelector: fieldSelector,
+	}, nil
+}
+
+func (s *objectSelector) matches(obj client.Object) bool {
+	if s.namespace != "" && obj.GetNamespace() != s.namespace {
+		return false
+	}
+
+	return s.labelSelector.Matches(labels.Set(obj.GetLabels())) && s.fieldSelector.Matches(fields.Set{
+		"metadata.name":      obj.GetName(),
+		"metadata.namespace": obj.GetNamespace(),
+	})
+}
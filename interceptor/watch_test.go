@@ -0,0 +1,82 @@
+package interceptor
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestWriteWatch(t *testing.T) {
+	// example.com/v1 is not registered in the scheme
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("example.com/v1")
+	obj.SetKind("Example")
+	obj.SetNamespace("default")
+	obj.SetName("test")
+
+	testCases := []struct {
+		name   string
+		url    string
+		events []watch.Event
+		types  []string
+	}{
+		{
+			name:   "unregistered group version",
+			url:    "/apis/example.com/v1/namespaces/default/examples?watch=true",
+			events: []watch.Event{{Type: watch.Added, Object: obj}, {Type: watch.Deleted, Object: obj}},
+			types:  []string{"ADDED", "DELETED"},
+		},
+		{
+			name:   "bookmarks are skipped if not allowed",
+			url:    "/apis/example.com/v1/namespaces/default/examples?watch=true",
+			events: []watch.Event{{Type: watch.Bookmark, Object: obj}, {Type: watch.Modified, Object: obj}},
+			types:  []string{"MODIFIED"},
+		},
+		{
+			name:   "bookmarks are sent if allowed",
+			url:    "/apis/example.com/v1/namespaces/default/examples?watch=true&allowWatchBookmarks=true",
+			events: []watch.Event{{Type: watch.Bookmark, Object: obj}},
+			types:  []string{"BOOKMARK"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			watcher := watch.NewFakeWithChanSize(len(testCase.events), false)
+			for _, event := range testCase.events {
+				watcher.Action(event.Type, event.Object)
+			}
+			watcher.Stop()
+
+			w := httptest.NewRecorder()
+			WriteWatch(w, httptest.NewRequest("GET", testCase.url, nil), watcher)
+			if w.Code != 200 {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			types := []string{}
+			decoder := json.NewDecoder(w.Body)
+			for decoder.More() {
+				event := struct {
+					Type   string                 `json:"type"`
+					Object map[string]interface{} `json:"object"`
+				}{}
+				err := decoder.Decode(&event)
+				if err != nil {
+					t.Fatalf("decode event: %v", err)
+				} else if event.Object["apiVersion"] != "example.com/v1" {
+					t.Fatalf("expected embedded example.com/v1 object, got %v", event.Object)
+				}
+
+				types = append(types, event.Type)
+			}
+			if strings.Join(types, ",") != strings.Join(testCase.types, ",") {
+				t.Fatalf("expected events %v, got %v", testCase.types, types)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/loft-sh/vcluster/pkg/util/clienthelper"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AuditRecord describes a single change a client hook made to an object
+type AuditRecord struct {
+	// Timestamp is the time the hook was called
+	Timestamp time.Time `json:"timestamp"`
+
+	// Hook is the name of the hook that changed the object
+	Hook string `json:"hook"`
+
+	// APIVersion is the api version of the changed object
+	APIVersion string `json:"apiVersion"`
+
+	// Kind is the kind of the changed object
+	Kind string `json:"kind"`
+
+	// Type is the hook type, e.g. CreatePhysical
+	Type string `json:"type"`
+
+	// Namespace is the namespace of the changed object
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the changed object
+	Name string `json:"name,omitempty"`
+
+	// Patch is the json merge patch the hook applied to the object
+	Patch json.RawMessage `json:"patch"`
+}
+
+// AuditSink receives a record for each hook invocation that changed an object. Sinks
+// are configured through Options.AuditSinks, auditing is disabled if there are none.
+type AuditSink interface {
+	Record(ctx context.Context, record AuditRecord)
+}
+
+// NewLogAuditSink returns an audit sink that writes each record to the plugin log
+func NewLogAuditSink() AuditSink {
+	return &logAuditSink{}
+}
+
+type logAuditSink struct{}
+
+func (l *logAuditSink) Record(ctx context.Context, record AuditRecord) {
+	klog.FromContext(ctx).Info("hook mutated object",
+		"hook", record.Hook,
+		"apiVersion", record.APIVersion,
+		"kind", record.Kind,
+		"type", record.Type,
+		"namespace", record.Namespace,
+		"name", record.Name,
+		"patch", string(record.Patch),
+	)
+}
+
+// RingBufferAuditSink keeps the last records in memory. It implements http.Handler and
+// returns the stored records as json, so it can be exposed as debug endpoint, for example
+// through an Interceptor with a nonResourceURL rule.
+type RingBufferAuditSink struct {
+	m sync.Mutex
+
+	records []AuditRecord
+	next    int
+	full    bool
+}
+
+// NewRingBufferAuditSink creates a new audit sink that keeps the last size records
+func NewRingBufferAuditSink(size int) *RingBufferAuditSink {
+	if size <= 0 {
+		size = 1
+	}
+
+	return &RingBufferAuditSink{
+		records: make([]AuditRecord, size),
+	}
+}
+
+func (r *RingBufferAuditSink) Record(_ context.Context, record AuditRecord) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.records[r.next] = record
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Records returns the stored records, oldest first
+func (r *RingBufferAuditSink) Records() []AuditRecord {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if !r.full {
+		return append([]AuditRecord{}, r.records[:r.next]...)
+	}
+
+	return append(append([]AuditRecord{}, r.records[r.next:]...), r.records[:r.next]...)
+}
+
+func (r *RingBufferAuditSink) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	out, err := json.Marshal(r.Records())
+	if err != nil {
+		responsewriters.InternalError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// audit sends a record for the change between before and after to all configured sinks
+func (p *pluginServer) audit(ctx context.Context, hookName, apiVersion, kind, hookType string, obj client.Object, before, after []byte) {
+	if len(p.auditSinks) == 0 {
+		return
+	}
+
+	patch, err := jsonpatch.CreateMergePatch(before, after)
+	if err != nil {
+		klog.FromContext(ctx).Error(err, "error creating audit patch", "hook", hookName)
+		return
+	}
+	if string(patch) == "{}" {
+		// only the encoding changed
+		return
+	}
+
+	record := AuditRecord{
+		Timestamp:  time.Now(),
+		Hook:       hookName,
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Type:       hookType,
+		Patch:      patch,
+	}
+	if !clienthelper.IsNilObject(obj) {
+		record.Namespace = obj.GetNamespace()
+		record.Name = obj.GetName()
+	}
+	for _, sink := range p.auditSinks {
+		sink.Record(ctx, record)
+	}
+}
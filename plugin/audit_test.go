@@ -0,0 +1,76 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestAudit(t *testing.T) {
+	testCases := []struct {
+		name          string
+		obj           client.Object
+		before, after string
+
+		expectedPatch     string
+		expectedName      string
+		expectedNamespace string
+	}{
+		{
+			name:              "change",
+			obj:               &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			before:            `{"metadata":{"name":"test"}}`,
+			after:             `{"metadata":{"name":"test","labels":{"a":"b"}}}`,
+			expectedPatch:     `{"metadata":{"labels":{"a":"b"}}}`,
+			expectedName:      "test",
+			expectedNamespace: "default",
+		},
+		{
+			name:   "no change",
+			obj:    &corev1.Pod{},
+			before: `{"metadata":{"name":"test"}}`,
+			after:  `{"metadata":{"name":"test"}}`,
+		},
+		{
+			name:          "typed nil object",
+			obj:           (*corev1.Pod)(nil),
+			before:        `{"metadata":{"name":"test"}}`,
+			after:         `{"metadata":{"name":"other"}}`,
+			expectedPatch: `{"metadata":{"name":"other"}}`,
+		},
+		{
+			name:          "nil object",
+			before:        `{"metadata":{"name":"test"}}`,
+			after:         `{"metadata":{"name":"other"}}`,
+			expectedPatch: `{"metadata":{"name":"other"}}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sink := NewRingBufferAuditSink(1)
+			p := &pluginServer{auditSinks: []AuditSink{sink}}
+			p.audit(context.Background(), "hook", "v1", "Pod", "UpdateVirtual", testCase.obj, []byte(testCase.before), []byte(testCase.after))
+
+			records := sink.Records()
+			if testCase.expectedPatch == "" {
+				if len(records) != 0 {
+					t.Fatalf("expected no record, got %v", records)
+				}
+				return
+			} else if len(records) != 1 {
+				t.Fatalf("expected one record, got %v", records)
+			}
+
+			record := records[0]
+			if string(record.Patch) != testCase.expectedPatch {
+				t.Fatalf("expected patch %s, got %s", testCase.expectedPatch, string(record.Patch))
+			} else if record.Name != testCase.expectedName || record.Namespace != testCase.expectedNamespace {
+				t.Fatalf("expected %s/%s, got %s/%s", testCase.expectedNamespace, testCase.expectedName, record.Namespace, record.Name)
+			}
+		})
+	}
+}
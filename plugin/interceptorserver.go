@@ -0,0 +1,169 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/klog/v2"
+)
+
+// Default timeouts of the interceptor server, see Options
+const (
+	DefaultInterceptorReadTimeout  = time.Minute
+	DefaultInterceptorWriteTimeout = time.Minute
+	DefaultInterceptorIdleTimeout  = 2 * time.Minute
+)
+
+var (
+	// longRunningVerbs and longRunningSubresources mark requests the read and write timeouts
+	// do not apply to, the same way the api server does
+	longRunningVerbs        = sets.NewString("watch", "proxy")
+	longRunningSubresources = sets.NewString("attach", "exec", "proxy", "log", "portforward")
+
+	// interceptorShutdownTimeout is the time in flight requests get to finish when the
+	// plugin stops
+	interceptorShutdownTimeout = 10 * time.Second
+)
+
+// interceptorServer serves the interceptors and restarts the listener with backoff if
+// serving fails
+type interceptorServer struct {
+	server *http.Server
+	port   int
+
+	listener net.Listener
+}
+
+// newInterceptorServer binds the listener for the interceptors, so binding errors can be
+// reported to vCluster before the plugin signals it is ready
+func newInterceptorServer(handler http.Handler, port int, options Options) (*interceptorServer, error) {
+	listener, err := listenForInterceptors(port)
+	if err != nil {
+		return nil, fmt.Errorf("listen for interceptors: %w", err)
+	}
+
+	return &interceptorServer{
+		server: &http.Server{
+			Handler:           withLongRunning(handler),
+			ReadHeaderTimeout: durationOrDefault(options.InterceptorReadTimeout, DefaultInterceptorReadTimeout),
+			ReadTimeout:       durationOrDefault(options.InterceptorReadTimeout, DefaultInterceptorReadTimeout),
+			WriteTimeout:      durationOrDefault(options.InterceptorWriteTimeout, DefaultInterceptorWriteTimeout),
+			IdleTimeout:       durationOrDefault(options.InterceptorIdleTimeout, DefaultInterceptorIdleTimeout),
+		},
+		port:     port,
+		listener: listener,
+	}, nil
+}
+
+// listenForInterceptors listens on the localhost port vCluster proxies intercepted requests
+// to. vCluster connects over plain http without authentication and does not negotiate
+// another transport, so every process in the network namespace of the pod can call the
+// interceptors. The port must never be exposed.
+func listenForInterceptors(port int) (net.Listener, error) {
+	return net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+}
+
+// run serves the interceptors until the context is done and shuts the server down
+// afterwards. If serving fails, the listener is recreated with exponential backoff.
+func (s *interceptorServer) run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), interceptorShutdownTimeout)
+		defer cancel()
+		err := s.server.Shutdown(shutdownCtx)
+		if err != nil {
+			klog.Errorf("error shutting down interceptor server: %v", err)
+		}
+	}()
+
+	backoff := newInterceptorBackoff()
+	for {
+		started := time.Now()
+		err := s.server.Serve(s.listener)
+		if errors.Is(err, http.ErrServerClosed) || ctx.Err() != nil {
+			return
+		}
+		klog.Errorf("error serving interceptors, restarting: %v", err)
+
+		// the server ran fine for a while, so start over with the backoff
+		if time.Since(started) > backoff.Cap {
+			backoff = newInterceptorBackoff()
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff.Step()):
+			}
+
+			s.listener, err = listenForInterceptors(s.port)
+			if err == nil {
+				break
+			}
+
+			klog.Errorf("error listening for interceptors, retrying: %v", err)
+		}
+	}
+}
+
+func newInterceptorBackoff() wait.Backoff {
+	return wait.Backoff{
+		Duration: time.Second,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    math.MaxInt32,
+		Cap:      30 * time.Second,
+	}
+}
+
+// interceptorHandler dispatches the requests to the interceptor with the name vCluster set
+// in the handler name header
+func (m *manager) interceptorHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerName := r.Header.Get(HandlerNameHeader)
+		if handlerName == "" {
+			responsewriters.InternalError(w, r, errors.New("header VCluster-Plugin-Handler-Name wasn't set"))
+			return
+		}
+		interceptorHandler, ok := m.interceptorsHandlers[handlerName]
+		if !ok {
+			responsewriters.InternalError(w, r, errors.New("header VCluster-Plugin-Handler-Name had no match"))
+			return
+		}
+		interceptorHandler.ServeHTTP(w, r)
+	})
+}
+
+// withLongRunning removes the read and write deadlines for watches and other long running
+// requests
+func withLongRunning(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, err := RequestInfo(r)
+		if err == nil && (longRunningVerbs.Has(info.Verb) || (info.IsResourceRequest && longRunningSubresources.Has(info.Subresource))) {
+			responseController := http.NewResponseController(w)
+			_ = responseController.SetReadDeadline(time.Time{})
+			_ = responseController.SetWriteDeadline(time.Time{})
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func durationOrDefault(duration, defaultDuration time.Duration) time.Duration {
+	if duration == 0 {
+		return defaultDuration
+	}
+
+	return duration
+}
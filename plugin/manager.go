@@ -6,11 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"strconv"
 	"sync"
 
 	"github.com/ghodss/yaml"
 	"github.com/loft-sh/log/logr"
+	"github.com/loft-sh/vcluster-sdk/syncers"
 	config2 "github.com/loft-sh/vcluster/config"
 	"github.com/loft-sh/vcluster/pkg/config"
 	"github.com/loft-sh/vcluster/pkg/plugin"
@@ -24,7 +24,7 @@ import (
 	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
 	"github.com/loft-sh/vcluster/pkg/util/clienthelper"
 	"github.com/pkg/errors"
-	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
@@ -32,9 +32,14 @@ import (
 	ctrlmanager "sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+// HandlerNameHeader is set by vCluster on intercepted requests and holds the name of
+// the interceptor that should handle the request.
+const HandlerNameHeader = "VCluster-Plugin-Handler-Name"
+
 func newManager() Manager {
 	return &manager{
 		interceptorsHandlers: make(map[string]http.Handler),
+		interceptorRules:     NewRuleMatcher(),
 	}
 }
 
@@ -54,6 +59,7 @@ type manager struct {
 
 	interceptorsHandlers map[string]http.Handler
 	interceptors         []Interceptor
+	interceptorRules     *RuleMatcher
 	interceptorsPort     int
 
 	proConfig v2.InitConfigPro
@@ -98,7 +104,7 @@ func (m *manager) InitWithOptions(options Options) (*synccontext.RegisterContext
 
 	// create a new plugin server
 	var err error
-	m.pluginServer, err = newPluginServer()
+	m.pluginServer, err = newPluginServer(m.options)
 	if err != nil {
 		return nil, fmt.Errorf("create plugin server")
 	}
@@ -209,7 +215,11 @@ func (m *manager) Register(syncer syncertypes.Base) error {
 		if _, ok := m.interceptorsHandlers[int.Name()]; ok {
 			return fmt.Errorf("could not add the interceptor %s because its name is already in use", int.Name())
 		}
-		m.interceptorsHandlers[int.Name()] = int
+		err := m.interceptorRules.Add(int.Name(), int.InterceptionRules())
+		if err != nil {
+			return fmt.Errorf("could not add the interceptor %s: %w", int.Name(), err)
+		}
+		m.interceptorsHandlers[int.Name()] = withInterceptorRecovery(int.Name(), int)
 		m.interceptors = append(m.interceptors, int)
 	}
 
@@ -217,6 +227,73 @@ func (m *manager) Register(syncer syncertypes.Base) error {
 	return nil
 }
 
+func (m *manager) SyncCRDToHost(gvk schema.GroupVersionKind, options syncers.CRDOptions) error {
+	registerContext, err := m.registerContext()
+	if err != nil {
+		return err
+	}
+
+	crdSyncer, err := syncers.NewCRDSyncer(registerContext, gvk, options)
+	if err != nil {
+		return fmt.Errorf("create syncer for %s: %w", gvk.String(), err)
+	}
+
+	return m.Register(crdSyncer)
+}
+
+func (m *manager) ImportFromHost(gvk schema.GroupVersionKind, options syncers.FromHostOptions) error {
+	registerContext, err := m.registerContext()
+	if err != nil {
+		return err
+	}
+
+	fromHostSyncer, err := syncers.NewFromHost(registerContext, gvk, options)
+	if err != nil {
+		return fmt.Errorf("create from host syncer for %s: %w", gvk.String(), err)
+	}
+
+	return m.Register(fromHostSyncer)
+}
+
+func (m *manager) SyncClusterScoped(gvk schema.GroupVersionKind, options syncers.ClusterScopedOptions) error {
+	registerContext, err := m.registerContext()
+	if err != nil {
+		return err
+	}
+
+	clusterScopedSyncer, err := syncers.NewClusterScopedSyncer(registerContext, gvk, options)
+	if err != nil {
+		return fmt.Errorf("create cluster scoped syncer for %s: %w", gvk.String(), err)
+	}
+
+	return m.Register(clusterScopedSyncer)
+}
+
+func (m *manager) InstallChart(options syncers.ChartOptions) error {
+	chart, err := syncers.NewChart(options)
+	if err != nil {
+		return fmt.Errorf("create chart syncer: %w", err)
+	}
+
+	return m.Register(chart)
+}
+
+// registerContext returns the register context, which is only available after Init
+func (m *manager) registerContext() (*synccontext.RegisterContext, error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.context == nil {
+		return nil, fmt.Errorf("plugin manager is not initialized")
+	}
+
+	return m.context, nil
+}
+
+func (m *manager) InterceptorFor(r *http.Request) (string, bool) {
+	return m.interceptorRules.Match(r)
+}
+
 func (m *manager) Start() error {
 	err := m.start()
 	if err != nil {
@@ -236,24 +313,6 @@ func (m *manager) StartAsync() (<-chan struct{}, error) {
 	return m.context.Context.Done(), nil
 }
 
-func (m *manager) startInterceptors() error {
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		handlerName := r.Header.Get("VCluster-Plugin-Handler-Name")
-		if handlerName == "" {
-			responsewriters.InternalError(w, r, errors.New("header VCluster-Plugin-Handler-Name wasn't set"))
-			return
-		}
-		interceptorHandler, ok := m.interceptorsHandlers[handlerName]
-		if !ok {
-			responsewriters.InternalError(w, r, errors.New("header VCluster-Plugin-Handler-Name had no match"))
-			return
-		}
-		interceptorHandler.ServeHTTP(w, r)
-	})
-
-	return http.ListenAndServe("127.0.0.1:"+strconv.Itoa(m.interceptorsPort), handler)
-}
-
 func (m *manager) start() error {
 	m.m.Lock()
 	defer m.m.Unlock()
@@ -266,26 +325,25 @@ func (m *manager) start() error {
 	// find all hooks
 	hooks, err := m.findAllHooks()
 	if err != nil {
-		return fmt.Errorf("find all hooks: %w", err)
+		return m.failStart(fmt.Errorf("find all hooks: %w", err))
 	}
 
 	// find the interceptors
 	interceptors := m.findAllInterceptors()
+	if len(interceptors) > 0 {
+		// bind before signaling we are ready, so vCluster learns about port conflicts
+		interceptorServer, err := newInterceptorServer(m.interceptorHandler(), m.interceptorsPort, m.options)
+		if err != nil {
+			return m.failStart(err)
+		}
+
+		// we need to start them regardless of being the leader, since the traffic is
+		// directed to all replicas
+		go interceptorServer.run(m.context)
+	}
 
 	// signal we are ready
 	m.pluginServer.SetReady(hooks, interceptors, m.interceptorsPort)
-
-	if len(m.interceptors) > 0 {
-		go func() {
-			// we need to start them regardless of being the leader, since the traffic is
-			// directed to all replicas
-			err := m.startInterceptors()
-			if err != nil {
-				klog.Error(err, "error while running the http interceptors:")
-				os.Exit(1)
-			}
-		}()
-	}
 	// wait until we are leader to continue
 	<-m.pluginServer.IsLeader()
 
@@ -299,6 +357,14 @@ func (m *manager) start() error {
 				return errors.Wrapf(err, "register indices for %s syncer", s.Name())
 			}
 		}
+
+		healthChecker, ok := s.(HealthChecker)
+		if ok {
+			err := m.context.VirtualManager.AddHealthzCheck(s.Name(), healthChecker.Check)
+			if err != nil {
+				return errors.Wrapf(err, "add health check for %s syncer", s.Name())
+			}
+		}
 	}
 
 	// start the local manager
@@ -371,6 +437,12 @@ func (m *manager) start() error {
 	return nil
 }
 
+// failStart reports the error to vCluster as response to the initialize call
+func (m *manager) failStart(err error) error {
+	m.pluginServer.SetFailed(err)
+	return err
+}
+
 func (m *manager) findAllInterceptors() []Interceptor {
 	klog.Info("len of m.interceptor is : ", len(m.interceptors))
 	return m.interceptors
@@ -393,7 +465,8 @@ func (m *manager) findAllHooks() (map[types.VersionKindType][]ClientHook, error)
 
 		apiVersion, kind := gvk.ToAPIVersionAndKind()
 		_, ok = clientHook.(MutateCreatePhysical)
-		if ok {
+		_, isValidator := clientHook.(ValidateCreatePhysical)
+		if ok || isValidator {
 			apiVersionKindType := types.VersionKindType{
 				APIVersion: apiVersion,
 				Kind:       kind,
@@ -402,7 +475,8 @@ func (m *manager) findAllHooks() (map[types.VersionKindType][]ClientHook, error)
 			hooks[apiVersionKindType] = append(hooks[apiVersionKindType], clientHook)
 		}
 		_, ok = clientHook.(MutateUpdatePhysical)
-		if ok {
+		_, isValidator = clientHook.(ValidateUpdatePhysical)
+		if ok || isValidator {
 			apiVersionKindType := types.VersionKindType{
 				APIVersion: apiVersion,
 				Kind:       kind,
@@ -411,7 +485,8 @@ func (m *manager) findAllHooks() (map[types.VersionKindType][]ClientHook, error)
 			hooks[apiVersionKindType] = append(hooks[apiVersionKindType], clientHook)
 		}
 		_, ok = clientHook.(MutateDeletePhysical)
-		if ok {
+		_, isValidator = clientHook.(ValidateDeletePhysical)
+		if ok || isValidator {
 			apiVersionKindType := types.VersionKindType{
 				APIVersion: apiVersion,
 				Kind:       kind,
@@ -429,7 +504,8 @@ func (m *manager) findAllHooks() (map[types.VersionKindType][]ClientHook, error)
 			hooks[apiVersionKindType] = append(hooks[apiVersionKindType], clientHook)
 		}
 		_, ok = clientHook.(MutateCreateVirtual)
-		if ok {
+		_, isValidator = clientHook.(ValidateCreateVirtual)
+		if ok || isValidator {
 			apiVersionKindType := types.VersionKindType{
 				APIVersion: apiVersion,
 				Kind:       kind,
@@ -438,7 +514,8 @@ func (m *manager) findAllHooks() (map[types.VersionKindType][]ClientHook, error)
 			hooks[apiVersionKindType] = append(hooks[apiVersionKindType], clientHook)
 		}
 		_, ok = clientHook.(MutateUpdateVirtual)
-		if ok {
+		_, isValidator = clientHook.(ValidateUpdateVirtual)
+		if ok || isValidator {
 			apiVersionKindType := types.VersionKindType{
 				APIVersion: apiVersion,
 				Kind:       kind,
@@ -447,7 +524,8 @@ func (m *manager) findAllHooks() (map[types.VersionKindType][]ClientHook, error)
 			hooks[apiVersionKindType] = append(hooks[apiVersionKindType], clientHook)
 		}
 		_, ok = clientHook.(MutateDeleteVirtual)
-		if ok {
+		_, isValidator = clientHook.(ValidateDeleteVirtual)
+		if ok || isValidator {
 			apiVersionKindType := types.VersionKindType{
 				APIVersion: apiVersion,
 				Kind:       kind,
@@ -1,12 +1,15 @@
 package plugin
 
 import (
+	"net/http"
 	"os"
 	"time"
 
+	"github.com/loft-sh/vcluster-sdk/syncers"
 	v2 "github.com/loft-sh/vcluster/pkg/plugin/v2"
 	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
 	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/klog/v2"
 )
 
@@ -52,6 +55,58 @@ func Register(syncer syncertypes.Base) error {
 	return defaultManager.Register(syncer)
 }
 
+func MustSyncCRDToHost(gvk schema.GroupVersionKind, options syncers.CRDOptions) {
+	err := defaultManager.SyncCRDToHost(gvk, options)
+	if err != nil {
+		klog.Errorf("plugin must sync crd to host: %v", err)
+		Exit(1)
+	}
+}
+
+func SyncCRDToHost(gvk schema.GroupVersionKind, options syncers.CRDOptions) error {
+	return defaultManager.SyncCRDToHost(gvk, options)
+}
+
+func MustImportFromHost(gvk schema.GroupVersionKind, options syncers.FromHostOptions) {
+	err := defaultManager.ImportFromHost(gvk, options)
+	if err != nil {
+		klog.Errorf("plugin must import from host: %v", err)
+		Exit(1)
+	}
+}
+
+func ImportFromHost(gvk schema.GroupVersionKind, options syncers.FromHostOptions) error {
+	return defaultManager.ImportFromHost(gvk, options)
+}
+
+func MustSyncClusterScoped(gvk schema.GroupVersionKind, options syncers.ClusterScopedOptions) {
+	err := defaultManager.SyncClusterScoped(gvk, options)
+	if err != nil {
+		klog.Errorf("plugin must sync cluster scoped: %v", err)
+		Exit(1)
+	}
+}
+
+func SyncClusterScoped(gvk schema.GroupVersionKind, options syncers.ClusterScopedOptions) error {
+	return defaultManager.SyncClusterScoped(gvk, options)
+}
+
+func MustInstallChart(options syncers.ChartOptions) {
+	err := defaultManager.InstallChart(options)
+	if err != nil {
+		klog.Errorf("plugin must install chart: %v", err)
+		Exit(1)
+	}
+}
+
+func InstallChart(options syncers.ChartOptions) error {
+	return defaultManager.InstallChart(options)
+}
+
+func InterceptorFor(r *http.Request) (string, bool) {
+	return defaultManager.InterceptorFor(r)
+}
+
 func MustStart() {
 	err := defaultManager.Start()
 	if err != nil {
@@ -0,0 +1,206 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// DefaultHookPanicThreshold is the number of consecutive panics after which a hook
+// will be disabled, if not configured otherwise through Options.HookPanicThreshold.
+const DefaultHookPanicThreshold = 5
+
+// DefaultHookCooldown is the time after which a disabled hook is called again, if not
+// configured otherwise through Options.HookCooldown.
+const DefaultHookCooldown = time.Minute
+
+var (
+	hookPanics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vcluster_plugin_hook_panics_total",
+		Help: "Total number of panics recovered in plugin client hooks.",
+	}, []string{"hook", "type"})
+
+	hooksDisabled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vcluster_plugin_hooks_disabled",
+		Help: "Plugin client hooks that were disabled because they panicked repeatedly.",
+	}, []string{"hook", "type"})
+
+	interceptorPanics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vcluster_plugin_interceptor_panics_total",
+		Help: "Total number of panics recovered in plugin interceptors.",
+	}, []string{"interceptor"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(hookPanics, hooksDisabled, interceptorPanics)
+}
+
+// callHook calls the given hook function and converts a panic into an error. Panics are
+// counted and the hook is disabled if it panics too often in a row.
+func (p *pluginServer) callHook(ctx context.Context, hookName, hookType string, fn func() error) (retErr error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			if p.breaker.success(hookName, hookType) {
+				klog.FromContext(ctx).Info("enabled hook again after it succeeded", "hook", hookName, "type", hookType)
+				hooksDisabled.WithLabelValues(hookName, hookType).Set(0)
+			}
+			return
+		}
+
+		klog.FromContext(ctx).Error(fmt.Errorf("%v", r), "recovered panic in hook", "hook", hookName, "type", hookType, "stack", string(debug.Stack()))
+		hookPanics.WithLabelValues(hookName, hookType).Inc()
+		if p.breaker.failure(hookName, hookType) {
+			klog.FromContext(ctx).Error(nil, "disabled hook because it panicked repeatedly", "hook", hookName, "type", hookType)
+			hooksDisabled.WithLabelValues(hookName, hookType).Set(1)
+		}
+
+		retErr = fmt.Errorf("hook %s panicked: %v", hookName, r)
+	}()
+
+	return fn()
+}
+
+// callValidatingHook calls the given validating hook function like callHook. Validating hooks
+// are tracked separately from mutating hooks of the same type. While a validating hook is
+// disabled, it denies all requests, as skipping it would allow requests it is meant to deny.
+func (p *pluginServer) callValidatingHook(ctx context.Context, hookName, hookType string, fn func() error) error {
+	hookType = "Validate" + hookType
+	if p.breaker.isOpen(hookName, hookType) {
+		return fmt.Errorf("hook %s is disabled because it panicked repeatedly", hookName)
+	}
+
+	return p.callHook(ctx, hookName, hookType, fn)
+}
+
+// newHookBreaker creates a new circuit breaker that opens after threshold consecutive
+// failures. Once the cooldown elapsed, an open breaker lets a single call through and
+// closes again if it succeeds. A negative threshold disables the circuit breaker.
+func newHookBreaker(threshold int, cooldown time.Duration) *hookBreaker {
+	if threshold == 0 {
+		threshold = DefaultHookPanicThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultHookCooldown
+	}
+
+	return &hookBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		states:    map[hookBreakerKey]*hookBreakerState{},
+		now:       time.Now,
+	}
+}
+
+type hookBreakerKey struct {
+	name     string
+	hookType string
+}
+
+type hookBreakerState struct {
+	failures int
+
+	// retryAt is the time the next call is let through while the breaker is open
+	retryAt time.Time
+}
+
+type hookBreaker struct {
+	m sync.Mutex
+
+	threshold int
+	cooldown  time.Duration
+	states    map[hookBreakerKey]*hookBreakerState
+	now       func() time.Time
+}
+
+// isOpen returns true if the hook should not be called. After the cooldown elapsed, it
+// returns false once, so the next call decides if the breaker closes again.
+func (b *hookBreaker) isOpen(name, hookType string) bool {
+	if b.threshold < 0 {
+		return false
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	state, ok := b.states[hookBreakerKey{name: name, hookType: hookType}]
+	if !ok || state.failures < b.threshold {
+		return false
+	}
+
+	now := b.now()
+	if now.Before(state.retryAt) {
+		return true
+	}
+
+	// half open, let this call through and block the others until it failed again
+	// or the next cooldown elapsed
+	state.retryAt = now.Add(b.cooldown)
+	return false
+}
+
+// success records a success and returns true if the breaker closed because of it
+func (b *hookBreaker) success(name, hookType string) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	key := hookBreakerKey{name: name, hookType: hookType}
+	state, ok := b.states[key]
+	if !ok {
+		return false
+	}
+
+	delete(b.states, key)
+	return b.threshold > 0 && state.failures >= b.threshold
+}
+
+// failure records a failure and returns true if the breaker opened because of it
+func (b *hookBreaker) failure(name, hookType string) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	key := hookBreakerKey{name: name, hookType: hookType}
+	state, ok := b.states[key]
+	if !ok {
+		state = &hookBreakerState{}
+		b.states[key] = state
+	}
+
+	state.failures++
+	if b.threshold < 0 || state.failures < b.threshold {
+		return false
+	}
+
+	state.retryAt = b.now().Add(b.cooldown)
+	return state.failures == b.threshold
+}
+
+// withInterceptorRecovery recovers panics from the interceptor handler and answers the
+// request with an internal error instead of crashing the plugin.
+func withInterceptorRecovery(name string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			} else if rec == http.ErrAbortHandler {
+				// the handler wants to abort the response, which is handled by the http server
+				panic(rec)
+			}
+
+			klog.FromContext(r.Context()).Error(fmt.Errorf("%v", rec), "recovered panic in interceptor", "interceptor", name, "method", r.Method, "path", r.URL.Path, "stack", string(debug.Stack()))
+			interceptorPanics.WithLabelValues(name).Inc()
+			responsewriters.InternalError(w, r, fmt.Errorf("interceptor %s panicked: %v", name, rec))
+		}()
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHookBreaker(t *testing.T) {
+	// each step either calls the hook, which fails or succeeds, or lets time pass
+	type step struct {
+		wait    time.Duration
+		fail    bool
+		skipped bool
+	}
+
+	testCases := []struct {
+		name      string
+		threshold int
+		steps     []step
+	}{
+		{
+			name:      "opens after threshold failures",
+			threshold: 2,
+			steps:     []step{{fail: true}, {fail: true}, {skipped: true}},
+		},
+		{
+			name:      "success resets failures",
+			threshold: 2,
+			steps:     []step{{fail: true}, {}, {fail: true}, {}},
+		},
+		{
+			name:      "half opens after cooldown and closes on success",
+			threshold: 1,
+			steps:     []step{{fail: true}, {skipped: true}, {wait: time.Minute}, {}, {fail: true}, {skipped: true}},
+		},
+		{
+			name:      "stays open if the call after the cooldown fails",
+			threshold: 1,
+			steps:     []step{{fail: true}, {wait: time.Minute}, {fail: true}, {skipped: true}, {wait: 30 * time.Second}, {skipped: true}, {wait: 30 * time.Second}, {}},
+		},
+		{
+			name:      "negative threshold never opens",
+			threshold: -1,
+			steps:     []step{{fail: true}, {fail: true}, {fail: true}, {}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			now := time.Now()
+			breaker := newHookBreaker(testCase.threshold, time.Minute)
+			breaker.now = func() time.Time { return now }
+
+			for i, step := range testCase.steps {
+				if step.wait > 0 {
+					now = now.Add(step.wait)
+					continue
+				}
+
+				open := breaker.isOpen("hook", "CreateVirtual")
+				if open != step.skipped {
+					t.Fatalf("step %d: expected open %v, got %v", i, step.skipped, open)
+				} else if open {
+					continue
+				}
+
+				if step.fail {
+					breaker.failure("hook", "CreateVirtual")
+				} else {
+					breaker.success("hook", "CreateVirtual")
+				}
+			}
+		})
+	}
+}
+
+func TestHookBreakerHalfOpenLetsOneCallThrough(t *testing.T) {
+	now := time.Now()
+	breaker := newHookBreaker(1, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	if !breaker.failure("hook", "CreateVirtual") {
+		t.Fatalf("expected breaker to open")
+	}
+
+	now = now.Add(time.Minute)
+	if breaker.isOpen("hook", "CreateVirtual") {
+		t.Fatalf("expected breaker to let the first call through after the cooldown")
+	} else if !breaker.isOpen("hook", "CreateVirtual") {
+		t.Fatalf("expected breaker to block concurrent calls while half open")
+	} else if !breaker.success("hook", "CreateVirtual") {
+		t.Fatalf("expected breaker to close after a success")
+	} else if breaker.isOpen("hook", "CreateVirtual") {
+		t.Fatalf("expected breaker to be closed")
+	}
+}
@@ -0,0 +1,273 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	v2 "github.com/loft-sh/vcluster/pkg/plugin/v2"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// requestInfoFactory parses intercepted requests the same way the virtual cluster api
+// server does
+var requestInfoFactory = &request.RequestInfoFactory{
+	APIPrefixes:          sets.NewString("api", "apis"),
+	GrouplessAPIPrefixes: sets.NewString("api"),
+}
+
+// RequestInfo returns the request info of the given request. If the request info was
+// not already added to the request context, it is parsed from the request url.
+func RequestInfo(r *http.Request) (*request.RequestInfo, error) {
+	info, ok := request.RequestInfoFrom(r.Context())
+	if ok {
+		return info, nil
+	}
+
+	info, err := requestInfoFactory.NewRequestInfo(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse request info: %w", err)
+	}
+
+	return info, nil
+}
+
+// ValidateInterceptionRules checks the rules of the named interceptor the same way vCluster
+// does when loading the plugin, so mistakes surface when the interceptor is registered
+// instead of failing the vCluster startup.
+func ValidateInterceptionRules(name string, rules []v2.InterceptorRule) error {
+	if len(rules) == 0 {
+		return fmt.Errorf("interceptor %s defines no interception rules", name)
+	}
+
+	for i, rule := range rules {
+		err := validateInterceptionRule(rule)
+		if err != nil {
+			return fmt.Errorf("interceptor %s rule %d: %w", name, i, err)
+		}
+	}
+
+	return nil
+}
+
+func validateInterceptionRule(rule v2.InterceptorRule) error {
+	if len(rule.Verbs) == 0 {
+		return fmt.Errorf("verbs are empty")
+	} else if slices.Contains(rule.Verbs, "*") && len(rule.Verbs) > 1 {
+		return fmt.Errorf("verbs define both * and other verbs, please either specify * or a list of verbs")
+	} else if slices.Contains(rule.Verbs, "*") && len(rule.ResourceNames) > 0 && !slices.Contains(rule.ResourceNames, "*") {
+		return fmt.Errorf("resource names cannot be combined with the * verb, as vCluster never matches such rules, please specify a list of verbs")
+	}
+
+	if len(rule.NonResourceURLs) > 0 {
+		if len(rule.APIGroups) > 0 || len(rule.Resources) > 0 || len(rule.ResourceNames) > 0 {
+			return fmt.Errorf("non resource urls cannot be mixed with api groups, resources or resource names, please use separate rules")
+		}
+
+		for _, nonResourceURL := range rule.NonResourceURLs {
+			if nonResourceURL == "" || nonResourceURL == "*" {
+				return fmt.Errorf("non resource url %q is empty or only a wildcard", nonResourceURL)
+			} else if firstStar := strings.Index(nonResourceURL, "*"); firstStar > -1 && firstStar != len(nonResourceURL)-1 {
+				return fmt.Errorf("non resource url %s defines a wildcard not at the end of the url", nonResourceURL)
+			}
+		}
+
+		return nil
+	}
+
+	if len(rule.APIGroups) == 0 {
+		return fmt.Errorf("api groups are empty, please either specify * or a list of api groups")
+	} else if slices.Contains(rule.APIGroups, "*") && len(rule.APIGroups) > 1 {
+		return fmt.Errorf("api groups define both * and other api groups, please either specify * or a list of api groups")
+	}
+	if len(rule.Resources) == 0 {
+		return fmt.Errorf("resources are empty, please either specify * or a list of resources")
+	} else if slices.Contains(rule.Resources, "*") && len(rule.Resources) > 1 {
+		return fmt.Errorf("resources define both * and other resources, please either specify * or a list of resources")
+	}
+	for _, resource := range rule.Resources {
+		if strings.Contains(resource, "/") {
+			return fmt.Errorf("resource %s defines a subresource, which vCluster does not match on, a rule for the resource also matches its subresources", resource)
+		}
+	}
+	if slices.Contains(rule.ResourceNames, "*") && len(rule.ResourceNames) > 1 {
+		return fmt.Errorf("resource names define both * and other names, please either specify *, leave them empty or specify a list of names")
+	}
+
+	return nil
+}
+
+// RuleMatcher finds the interceptor that handles a request based on the interception rules
+// of all added interceptors. The plugin keeps a matcher of all registered interceptors,
+// which can be queried with InterceptorFor.
+type RuleMatcher struct {
+	m     sync.RWMutex
+	rules []namedRule
+}
+
+type namedRule struct {
+	name string
+	rule v2.InterceptorRule
+}
+
+// NewRuleMatcher creates a new empty rule matcher
+func NewRuleMatcher() *RuleMatcher {
+	return &RuleMatcher{}
+}
+
+// Add validates the rules of the named interceptor and adds them to the matcher. It fails
+// if a rule overlaps with a rule of this or a previously added interceptor, as vCluster
+// could not decide which interceptor should handle the request.
+func (r *RuleMatcher) Add(name string, rules []v2.InterceptorRule) error {
+	err := ValidateInterceptionRules(name, rules)
+	if err != nil {
+		return err
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	added := make([]namedRule, 0, len(rules))
+	for _, rule := range rules {
+		for _, existing := range slices.Concat(r.rules, added) {
+			if conflict := rulesOverlap(existing.rule, rule); conflict != "" {
+				if existing.name == name {
+					return fmt.Errorf("interceptor %s defines overlapping rules for %s", name, conflict)
+				}
+
+				return fmt.Errorf("interceptor %s conflicts with interceptor %s for %s", name, existing.name, conflict)
+			}
+		}
+
+		added = append(added, namedRule{name: name, rule: rule})
+	}
+
+	r.rules = append(r.rules, added...)
+	return nil
+}
+
+// Match returns the name of the interceptor that would handle the request
+func (r *RuleMatcher) Match(req *http.Request) (string, bool) {
+	info, err := RequestInfo(req)
+	if err != nil {
+		return "", false
+	}
+
+	return r.MatchRequestInfo(info)
+}
+
+// MatchRequestInfo returns the name of the interceptor that would handle a request with the
+// given request info
+func (r *RuleMatcher) MatchRequestInfo(info *request.RequestInfo) (string, bool) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	for _, rule := range r.rules {
+		if ruleMatches(rule.rule, info) {
+			return rule.name, true
+		}
+	}
+
+	return "", false
+}
+
+// ruleMatches matches the request like vCluster does. Only the resource of a request is
+// matched, so a rule for a resource also matches all of its subresources.
+func ruleMatches(rule v2.InterceptorRule, info *request.RequestInfo) bool {
+	if !matchesValue(rule.Verbs, info.Verb) {
+		return false
+	}
+
+	if !info.IsResourceRequest {
+		for _, nonResourceURL := range rule.NonResourceURLs {
+			if nonResourceURLMatches(nonResourceURL, info.Path) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return len(rule.NonResourceURLs) == 0 &&
+		matchesValue(rule.APIGroups, info.APIGroup) &&
+		matchesValue(rule.Resources, info.Resource) &&
+		(len(rule.ResourceNames) == 0 || matchesValue(rule.ResourceNames, info.Name))
+}
+
+func matchesValue(values []string, value string) bool {
+	return slices.Contains(values, "*") || slices.Contains(values, value)
+}
+
+func nonResourceURLMatches(nonResourceURL, path string) bool {
+	if prefix, ok := strings.CutSuffix(nonResourceURL, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+
+	return nonResourceURL == path
+}
+
+// rulesOverlap returns a description of the requests matched by both rules or an empty
+// string if there are none
+func rulesOverlap(a, b v2.InterceptorRule) string {
+	verb, ok := overlap(a.Verbs, b.Verbs)
+	if !ok {
+		return ""
+	}
+
+	if len(a.NonResourceURLs) > 0 || len(b.NonResourceURLs) > 0 {
+		for _, aURL := range a.NonResourceURLs {
+			for _, bURL := range b.NonResourceURLs {
+				if nonResourceURLMatches(aURL, strings.TrimSuffix(bURL, "*")) || nonResourceURLMatches(bURL, strings.TrimSuffix(aURL, "*")) {
+					return fmt.Sprintf("non resource url %s and verb %s", bURL, verb)
+				}
+			}
+		}
+
+		return ""
+	}
+
+	group, ok := overlap(a.APIGroups, b.APIGroups)
+	if !ok {
+		return ""
+	}
+	resource, ok := overlap(a.Resources, b.Resources)
+	if !ok {
+		return ""
+	}
+
+	// empty resource names match all names
+	resourceName, ok := overlap(resourceNamesOrWildcard(a.ResourceNames), resourceNamesOrWildcard(b.ResourceNames))
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("resource %s/%s, verb %s and resource name %s", group, resource, verb, resourceName)
+}
+
+// overlap returns a value matched by both lists, taking wildcards into account
+func overlap(a, b []string) (string, bool) {
+	if slices.Contains(a, "*") && len(b) > 0 {
+		return b[0], true
+	} else if slices.Contains(b, "*") && len(a) > 0 {
+		return a[0], true
+	}
+
+	for _, value := range a {
+		if slices.Contains(b, value) {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+func resourceNamesOrWildcard(resourceNames []string) []string {
+	if len(resourceNames) == 0 {
+		return []string{"*"}
+	}
+
+	return resourceNames
+}
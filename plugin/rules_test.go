@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v2 "github.com/loft-sh/vcluster/pkg/plugin/v2"
+)
+
+func TestRulesOverlap(t *testing.T) {
+	testCases := []struct {
+		name    string
+		a, b    v2.InterceptorRule
+		overlap bool
+	}{
+		{
+			name:    "same resource and verb",
+			a:       v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+			b:       v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+			overlap: true,
+		},
+		{
+			name: "different verbs",
+			a:    v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+			b:    v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}},
+		},
+		{
+			name: "different resources",
+			a:    v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"*"}},
+			b:    v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"services"}, Verbs: []string{"*"}},
+		},
+		{
+			name: "different api groups",
+			a:    v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"get"}},
+			b:    v2.InterceptorRule{APIGroups: []string{"apps"}, Resources: []string{"*"}, Verbs: []string{"get"}},
+		},
+		{
+			name:    "wildcard api group",
+			a:       v2.InterceptorRule{APIGroups: []string{"*"}, Resources: []string{"deployments"}, Verbs: []string{"get"}},
+			b:       v2.InterceptorRule{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get"}},
+			overlap: true,
+		},
+		{
+			name:    "wildcard resource",
+			a:       v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"delete"}},
+			b:       v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"delete"}},
+			overlap: true,
+		},
+		{
+			name: "different resource names",
+			a:    v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}, ResourceNames: []string{"a"}},
+			b:    v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}, ResourceNames: []string{"b"}},
+		},
+		{
+			name:    "empty resource names match all names",
+			a:       v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+			b:       v2.InterceptorRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}, ResourceNames: []string{"b"}},
+			overlap: true,
+		},
+		{
+			name:    "wildcard non resource url",
+			a:       v2.InterceptorRule{NonResourceURLs: []string{"/healthz*"}, Verbs: []string{"get"}},
+			b:       v2.InterceptorRule{NonResourceURLs: []string{"/healthz/ready"}, Verbs: []string{"get"}},
+			overlap: true,
+		},
+		{
+			name: "different non resource urls",
+			a:    v2.InterceptorRule{NonResourceURLs: []string{"/healthz"}, Verbs: []string{"get"}},
+			b:    v2.InterceptorRule{NonResourceURLs: []string{"/version"}, Verbs: []string{"get"}},
+		},
+		{
+			name: "non resource url and resource",
+			a:    v2.InterceptorRule{NonResourceURLs: []string{"/healthz"}, Verbs: []string{"get"}},
+			b:    v2.InterceptorRule{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"get"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			for _, rules := range [][2]v2.InterceptorRule{{testCase.a, testCase.b}, {testCase.b, testCase.a}} {
+				conflict := rulesOverlap(rules[0], rules[1])
+				if testCase.overlap != (conflict != "") {
+					t.Fatalf("expected overlap %v, got %q", testCase.overlap, conflict)
+				}
+			}
+		})
+	}
+}
+
+func TestRuleMatcherMatch(t *testing.T) {
+	matcher := NewRuleMatcher()
+	for name, rules := range map[string][]v2.InterceptorRule{
+		"pods":      {{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "update"}}},
+		"named":     {{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}, ResourceNames: []string{"special"}}},
+		"apps":      {{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"*"}}},
+		"healthz":   {{NonResourceURLs: []string{"/healthz*"}, Verbs: []string{"get"}}},
+		"version":   {{NonResourceURLs: []string{"/version"}, Verbs: []string{"*"}}},
+		"anyGroups": {{APIGroups: []string{"*"}, Resources: []string{"secrets"}, Verbs: []string{"list"}}},
+	} {
+		err := matcher.Add(name, rules)
+		if err != nil {
+			t.Fatalf("add %s: %v", name, err)
+		}
+	}
+
+	testCases := []struct {
+		method, path string
+		interceptor  string
+	}{
+		{method: "GET", path: "/api/v1/namespaces/default/pods/test", interceptor: "pods"},
+		{method: "PUT", path: "/api/v1/namespaces/default/pods/test", interceptor: "pods"},
+		{method: "DELETE", path: "/api/v1/namespaces/default/pods/test"},
+		{method: "GET", path: "/api/v1/namespaces/default/pods"},
+		// vCluster matches the resource only, so subresources are intercepted as well
+		{method: "PUT", path: "/api/v1/namespaces/default/pods/test/status", interceptor: "pods"},
+		{method: "GET", path: "/api/v1/namespaces/default/pods/test/log", interceptor: "pods"},
+		{method: "GET", path: "/api/v1/namespaces/default/configmaps/special", interceptor: "named"},
+		{method: "GET", path: "/api/v1/namespaces/default/configmaps/other"},
+		{method: "DELETE", path: "/apis/apps/v1/namespaces/default/deployments/test", interceptor: "apps"},
+		{method: "GET", path: "/apis/apps/v1/deployments?watch=true", interceptor: "apps"},
+		{method: "GET", path: "/api/v1/secrets", interceptor: "anyGroups"},
+		{method: "GET", path: "/apis/example.com/v1/namespaces/default/secrets", interceptor: "anyGroups"},
+		{method: "GET", path: "/healthz/ready", interceptor: "healthz"},
+		{method: "POST", path: "/healthz"},
+		{method: "POST", path: "/version", interceptor: "version"},
+		{method: "GET", path: "/version/other"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.method+" "+testCase.path, func(t *testing.T) {
+			interceptor, ok := matcher.Match(httptest.NewRequest(testCase.method, testCase.path, nil))
+			if ok != (testCase.interceptor != "") || interceptor != testCase.interceptor {
+				t.Fatalf("expected interceptor %q, got %q", testCase.interceptor, interceptor)
+			}
+		})
+	}
+}
+
+func TestRuleMatcherAdd(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rules []v2.InterceptorRule
+		err   string
+	}{
+		{
+			name:  "subresource",
+			rules: []v2.InterceptorRule{{APIGroups: []string{""}, Resources: []string{"pods/status"}, Verbs: []string{"update"}}},
+			err:   "subresource",
+		},
+		{
+			name:  "resource names with all verbs",
+			rules: []v2.InterceptorRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"*"}, ResourceNames: []string{"test"}}},
+			err:   "resource names cannot be combined",
+		},
+		{
+			name: "overlapping rules",
+			rules: []v2.InterceptorRule{
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+				{APIGroups: []string{"*"}, Resources: []string{"pods"}, Verbs: []string{"*"}},
+			},
+			err: "overlapping rules",
+		},
+		{
+			name: "valid rules",
+			rules: []v2.InterceptorRule{
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := NewRuleMatcher().Add("test", testCase.rules)
+			if testCase.err == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			} else if testCase.err != "" && (err == nil || !strings.Contains(err.Error(), testCase.err)) {
+				t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/loft-sh/vcluster/pkg/plugin/v2/pluginv2"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type server interface {
@@ -23,6 +24,10 @@ type server interface {
 	// SetReady signals the plugin server the plugin is ready to start
 	SetReady(hooks map[types.VersionKindType][]ClientHook, interceptors []Interceptor, port int)
 
+	// SetFailed signals the plugin server the plugin failed to start, the error is
+	// returned to vCluster
+	SetFailed(err error)
+
 	// Initialized retrieves the initialize request
 	Initialized() <-chan *pluginv2.Initialize_Request
 
@@ -30,10 +35,13 @@ type server interface {
 	IsLeader() <-chan struct{}
 }
 
-func newPluginServer() (server, error) {
+func newPluginServer(options Options) (server, error) {
 	return &pluginServer{
 		UnimplementedPluginServer: pluginv2.UnimplementedPluginServer{},
 
+		breaker:    newHookBreaker(options.HookPanicThreshold, options.HookCooldown),
+		auditSinks: options.AuditSinks,
+
 		initialized: make(chan *pluginv2.Initialize_Request),
 		isReady:     make(chan struct{}),
 		isLeader:    make(chan struct{}),
@@ -47,9 +55,13 @@ type pluginServer struct {
 	interceptors     []Interceptor
 	interceptorsPort int
 
+	breaker    *hookBreaker
+	auditSinks []AuditSink
+
 	initialized chan *pluginv2.Initialize_Request
 	isReady     chan struct{}
 	isLeader    chan struct{}
+	startErr    error
 }
 
 var _ pluginv2.PluginServer = &pluginServer{}
@@ -72,6 +84,9 @@ func (p *pluginServer) Initialize(ctx context.Context, initRequest *pluginv2.Ini
 
 	// wait for plugin to become ready
 	<-p.isReady
+	if p.startErr != nil {
+		return nil, fmt.Errorf("start plugin: %w", p.startErr)
+	}
 
 	// return back to syncer
 	return &pluginv2.Initialize_Response{}, nil
@@ -97,6 +112,11 @@ func (p *pluginServer) SetReady(hooks map[types.VersionKindType][]ClientHook, in
 	close(p.isReady)
 }
 
+func (p *pluginServer) SetFailed(err error) {
+	p.startErr = err
+	close(p.isReady)
+}
+
 func (p *pluginServer) Mutate(ctx context.Context, req *pluginv2.Mutate_Request) (*pluginv2.Mutate_Response, error) {
 	hooks, ok := p.hooks[types.VersionKindType{
 		APIVersion: req.ApiVersion,
@@ -111,12 +131,7 @@ func (p *pluginServer) Mutate(ctx context.Context, req *pluginv2.Mutate_Request)
 	originalObject := object
 
 	for _, h := range hooks {
-		res := h.Resource()
-		err := json.Unmarshal([]byte(object), res)
-		if err != nil {
-			return nil, fmt.Errorf("error decoding object: %v", err)
-		}
-
+		var mutate func(ctx context.Context, obj client.Object) (client.Object, error)
 		switch req.Type {
 		case "CreatePhysical":
 			m, ok := h.(MutateCreatePhysical)
@@ -124,96 +139,179 @@ func (p *pluginServer) Mutate(ctx context.Context, req *pluginv2.Mutate_Request)
 				continue
 			}
 
-			res, err = m.MutateCreatePhysical(ctx, res)
-			if err != nil {
-				return nil, fmt.Errorf("error mutating object: %v", err)
-			}
+			mutate = m.MutateCreatePhysical
 		case "UpdatePhysical":
 			m, ok := h.(MutateUpdatePhysical)
 			if !ok {
 				continue
 			}
 
-			res, err = m.MutateUpdatePhysical(ctx, res)
-			if err != nil {
-				return nil, fmt.Errorf("error mutating object: %v", err)
-			}
+			mutate = m.MutateUpdatePhysical
 		case "DeletePhysical":
 			m, ok := h.(MutateDeletePhysical)
 			if !ok {
 				continue
 			}
 
-			res, err = m.MutateDeletePhysical(ctx, res)
-			if err != nil {
-				return nil, fmt.Errorf("error mutating object: %v", err)
-			}
+			mutate = m.MutateDeletePhysical
 		case "GetPhysical":
 			m, ok := h.(MutateGetPhysical)
 			if !ok {
 				continue
 			}
 
-			res, err = m.MutateGetPhysical(ctx, res)
-			if err != nil {
-				return nil, fmt.Errorf("error mutating object: %v", err)
-			}
+			mutate = m.MutateGetPhysical
 		case "CreateVirtual":
 			m, ok := h.(MutateCreateVirtual)
 			if !ok {
 				continue
 			}
 
-			res, err = m.MutateCreateVirtual(ctx, res)
-			if err != nil {
-				return nil, fmt.Errorf("error mutating object: %v", err)
-			}
+			mutate = m.MutateCreateVirtual
 		case "UpdateVirtual":
 			m, ok := h.(MutateUpdateVirtual)
 			if !ok {
 				continue
 			}
 
-			res, err = m.MutateUpdateVirtual(ctx, res)
-			if err != nil {
-				return nil, fmt.Errorf("error mutating object: %v", err)
-			}
+			mutate = m.MutateUpdateVirtual
 		case "DeleteVirtual":
 			m, ok := h.(MutateDeleteVirtual)
 			if !ok {
 				continue
 			}
 
-			res, err = m.MutateDeleteVirtual(ctx, res)
-			if err != nil {
-				return nil, fmt.Errorf("error mutating object: %v", err)
-			}
+			mutate = m.MutateDeleteVirtual
 		case "GetVirtual":
 			m, ok := h.(MutateGetVirtual)
 			if !ok {
 				continue
 			}
 
-			res, err = m.MutateGetVirtual(ctx, res)
+			mutate = m.MutateGetVirtual
+		default:
+			continue
+		}
+		if p.breaker.isOpen(h.Name(), req.Type) {
+			continue
+		}
+
+		res := h.Resource()
+		err := json.Unmarshal([]byte(object), res)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding object: %v", err)
+		}
+
+		// audit against the decoded object, so differences in the encoding are not recorded
+		// as changes of the hook
+		var before []byte
+		if len(p.auditSinks) > 0 {
+			before, err = json.Marshal(res)
 			if err != nil {
-				return nil, fmt.Errorf("error mutating object: %v", err)
+				return nil, fmt.Errorf("error encoding object %#+v: %v", res, err)
 			}
 		}
 
+		err = p.callHook(ctx, h.Name(), req.Type, func() error {
+			res, err = mutate(ctx, res)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error mutating object: %v", err)
+		}
+
 		rawObject, err := json.Marshal(res)
 		if err != nil {
 			return nil, fmt.Errorf("error encoding object %#+v: %v", res, err)
 		}
 
+		if len(p.auditSinks) > 0 && string(rawObject) != string(before) {
+			p.audit(ctx, h.Name(), req.ApiVersion, req.Kind, req.Type, res, before, rawObject)
+		}
+
 		object = string(rawObject)
 	}
 
+	// run validating hooks on the final object
+	err := p.validate(ctx, req.Type, hooks, object)
+	if err != nil {
+		return nil, err
+	}
+
 	if object == originalObject {
 		return &pluginv2.Mutate_Response{}, nil
 	}
 	return &pluginv2.Mutate_Response{Mutated: true, Object: object}, nil
 }
 
+func (p *pluginServer) validate(ctx context.Context, hookType string, hooks []ClientHook, object string) error {
+	for _, h := range hooks {
+		var validate func(ctx context.Context, obj client.Object) error
+		switch hookType {
+		case "CreatePhysical":
+			v, ok := h.(ValidateCreatePhysical)
+			if !ok {
+				continue
+			}
+
+			validate = v.ValidateCreatePhysical
+		case "UpdatePhysical":
+			v, ok := h.(ValidateUpdatePhysical)
+			if !ok {
+				continue
+			}
+
+			validate = v.ValidateUpdatePhysical
+		case "DeletePhysical":
+			v, ok := h.(ValidateDeletePhysical)
+			if !ok {
+				continue
+			}
+
+			validate = v.ValidateDeletePhysical
+		case "CreateVirtual":
+			v, ok := h.(ValidateCreateVirtual)
+			if !ok {
+				continue
+			}
+
+			validate = v.ValidateCreateVirtual
+		case "UpdateVirtual":
+			v, ok := h.(ValidateUpdateVirtual)
+			if !ok {
+				continue
+			}
+
+			validate = v.ValidateUpdateVirtual
+		case "DeleteVirtual":
+			v, ok := h.(ValidateDeleteVirtual)
+			if !ok {
+				continue
+			}
+
+			validate = v.ValidateDeleteVirtual
+		default:
+			return nil
+		}
+
+		// each validator gets its own copy, so they cannot influence each other
+		res := h.Resource()
+		err := json.Unmarshal([]byte(object), res)
+		if err != nil {
+			return fmt.Errorf("error decoding object: %v", err)
+		}
+
+		err = p.callValidatingHook(ctx, h.Name(), hookType, func() error {
+			return validate(ctx, res)
+		})
+		if err != nil {
+			return fmt.Errorf("denied by hook %s: %w", h.Name(), err)
+		}
+	}
+
+	return nil
+}
+
 func (p *pluginServer) GetPluginConfig(context.Context, *pluginv2.GetPluginConfig_Request) (*pluginv2.GetPluginConfig_Response, error) {
 	clientHooks, err := p.getClientHooks()
 	if err != nil {
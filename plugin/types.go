@@ -3,11 +3,14 @@ package plugin
 import (
 	"context"
 	"net/http"
+	"time"
 
+	"github.com/loft-sh/vcluster-sdk/syncers"
 	"github.com/loft-sh/vcluster/pkg/mappings/resources"
 	v2 "github.com/loft-sh/vcluster/pkg/plugin/v2"
 	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
 	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlmanager "sigs.k8s.io/controller-runtime/pkg/manager"
 )
@@ -21,6 +24,30 @@ type Options struct {
 
 	// RegisterMappings will start the default mappings
 	RegisterMappings []resources.BuildMapper
+
+	// HookPanicThreshold is the number of consecutive panics after which a client hook
+	// gets disabled. Disabled mutating hooks are skipped, disabled validating hooks deny
+	// all requests. Defaults to DefaultHookPanicThreshold, a negative value never
+	// disables hooks.
+	HookPanicThreshold int
+
+	// HookCooldown is the time after which a disabled client hook is called again. If
+	// the call succeeds, the hook is enabled again, otherwise it stays disabled for
+	// another cooldown. Defaults to DefaultHookCooldown.
+	HookCooldown time.Duration
+
+	// AuditSinks enables auditing of client hooks. Each change a hook makes to an object
+	// is recorded as json merge patch and sent to all sinks.
+	AuditSinks []AuditSink
+
+	// InterceptorReadTimeout, InterceptorWriteTimeout and InterceptorIdleTimeout configure
+	// the server the interceptors are served with. They default to DefaultInterceptorReadTimeout,
+	// DefaultInterceptorWriteTimeout and DefaultInterceptorIdleTimeout, a negative value
+	// disables the timeout. The read and write timeouts do not apply to watches and other
+	// long running requests.
+	InterceptorReadTimeout  time.Duration
+	InterceptorWriteTimeout time.Duration
+	InterceptorIdleTimeout  time.Duration
 }
 
 type Manager interface {
@@ -36,6 +63,26 @@ type Manager interface {
 	// is run.
 	Register(syncer syncertypes.Base) error
 
+	// SyncCRDToHost registers a syncer that syncs the custom resources of the given kind
+	// to the host cluster, see syncers.NewCRDSyncer. Needs to be called after Init.
+	SyncCRDToHost(gvk schema.GroupVersionKind, options syncers.CRDOptions) error
+
+	// ImportFromHost registers a syncer that imports the host objects of the given kind
+	// into the virtual cluster, see syncers.NewFromHost. Needs to be called after Init.
+	ImportFromHost(gvk schema.GroupVersionKind, options syncers.FromHostOptions) error
+
+	// SyncClusterScoped registers a syncer for a cluster scoped kind, see
+	// syncers.NewClusterScopedSyncer. Needs to be called after Init.
+	SyncClusterScoped(gvk schema.GroupVersionKind, options syncers.ClusterScopedOptions) error
+
+	// InstallChart registers a syncer that installs a helm chart into the virtual cluster,
+	// see syncers.NewChart.
+	InstallChart(options syncers.ChartOptions) error
+
+	// InterceptorFor returns the name of the registered interceptor whose rules match
+	// the given request.
+	InterceptorFor(r *http.Request) (string, bool)
+
 	// Start runs all the registered syncers and will block. It only executes
 	// the functionality if the current vcluster pod is the current leader and
 	// will stop if the pod will lose leader election.
@@ -59,9 +106,12 @@ type Manager interface {
 // to mutate these. The objects this action wants to watch can be defined through the
 // Resource() function that returns a new object of the type to watch. By implementing
 // the defined interfaces below it is possible to watch on:
-// Create, Update (includes patch requests), Delete and Get requests.
+// Create, Update (includes patch requests and writes to the status subresource), Delete
+// and Get requests.
 // This makes it possible to change incoming or outgoing objects on the fly, without the
 // need to completely replace a vanilla vcluster syncer.
+// Validating hooks (Validate*) are called after all mutating hooks and can only allow
+// or deny a request.
 type ClientHook interface {
 	syncertypes.Base
 
@@ -69,6 +119,16 @@ type ClientHook interface {
 	Resource() client.Object
 }
 
+// HealthChecker is a syncer that reports its health through the health endpoint of the
+// virtual manager, which needs to be enabled with Options.ModifyVirtualManager by setting
+// a HealthProbeBindAddress. The check is added as soon as the plugin became leader.
+type HealthChecker interface {
+	syncertypes.Base
+
+	// Check returns an error if the syncer is unhealthy
+	Check(req *http.Request) error
+}
+
 type Interceptor interface {
 	syncertypes.Base
 
@@ -110,3 +170,39 @@ type MutateDeletePhysical interface {
 type MutateGetPhysical interface {
 	MutateGetPhysical(ctx context.Context, obj client.Object) (client.Object, error)
 }
+
+// ValidateCreateVirtual is called after all mutating hooks ran and can deny the creation of
+// a virtual object by returning an error. The object passed must not be modified.
+type ValidateCreateVirtual interface {
+	ValidateCreateVirtual(ctx context.Context, obj client.Object) error
+}
+
+// ValidateUpdateVirtual is called after all mutating hooks ran and can deny the update of
+// a virtual object by returning an error. The object passed must not be modified.
+type ValidateUpdateVirtual interface {
+	ValidateUpdateVirtual(ctx context.Context, obj client.Object) error
+}
+
+// ValidateDeleteVirtual is called after all mutating hooks ran and can deny the deletion of
+// a virtual object by returning an error. The object passed must not be modified.
+type ValidateDeleteVirtual interface {
+	ValidateDeleteVirtual(ctx context.Context, obj client.Object) error
+}
+
+// ValidateCreatePhysical is called after all mutating hooks ran and can deny the creation of
+// a host object by returning an error. The object passed must not be modified.
+type ValidateCreatePhysical interface {
+	ValidateCreatePhysical(ctx context.Context, obj client.Object) error
+}
+
+// ValidateUpdatePhysical is called after all mutating hooks ran and can deny the update of
+// a host object by returning an error. The object passed must not be modified.
+type ValidateUpdatePhysical interface {
+	ValidateUpdatePhysical(ctx context.Context, obj client.Object) error
+}
+
+// ValidateDeletePhysical is called after all mutating hooks ran and can deny the deletion of
+// a host object by returning an error. The object passed must not be modified.
+type ValidateDeletePhysical interface {
+	ValidateDeletePhysical(ctx context.Context, obj client.Object) error
+}
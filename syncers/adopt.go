@@ -0,0 +1,209 @@
+package syncers
+
+import (
+	"fmt"
+
+	"github.com/loft-sh/vcluster/pkg/patcher"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
+	"github.com/loft-sh/vcluster/pkg/util/clienthelper"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AdoptionPolicy defines what happens to host objects that were synced by a previous
+// incarnation of the vCluster, e.g. after it was recreated, and have no virtual object
+type AdoptionPolicy string
+
+const (
+	// AdoptionDelete deletes host objects without a virtual object, this is the default
+	AdoptionDelete AdoptionPolicy = "Delete"
+
+	// AdoptionIgnore leaves host objects without a virtual object untouched
+	AdoptionIgnore AdoptionPolicy = "Ignore"
+
+	// AdoptionAdopt creates the virtual object of host objects without one and lets
+	// recreated virtual objects take over the host object of the same name
+	AdoptionAdopt AdoptionPolicy = "Adopt"
+)
+
+// Adopter implements syncertypes.Importer for the syncers of this package. Host objects are
+// matched to their virtual objects through the name, namespace, kind and uid annotations
+// vCluster sets on synced objects. A host object is only considered orphaned if the syncer
+// hasn't seen its virtual object being deleted, so after a restart of the plugin, host
+// objects of virtual objects deleted in the meantime are treated as orphans as well.
+type Adopter struct {
+	gvk           schema.GroupVersionKind
+	policy        AdoptionPolicy
+	eventRecorder events.EventRecorder
+}
+
+var _ syncertypes.Importer = &Adopter{}
+
+// NewAdopter creates a new adopter for the kind. The policy defaults to AdoptionDelete.
+func NewAdopter(gvk schema.GroupVersionKind, policy AdoptionPolicy, eventRecorder events.EventRecorder) (*Adopter, error) {
+	if policy == "" {
+		policy = AdoptionDelete
+	} else if policy != AdoptionDelete && policy != AdoptionIgnore && policy != AdoptionAdopt {
+		return nil, fmt.Errorf("unsupported adoption policy %s", policy)
+	}
+
+	return &Adopter{
+		gvk:           gvk,
+		policy:        policy,
+		eventRecorder: eventRecorder,
+	}, nil
+}
+
+// DisableUIDDeletion returns if host objects whose uid annotation doesn't match the virtual
+// object should be kept, see syncertypes.Options
+func (a *Adopter) DisableUIDDeletion() bool {
+	return a.policy == AdoptionAdopt
+}
+
+// Import is called for host objects that are not managed by the vCluster, e.g. because
+// their marker label is missing or their name was translated differently. Orphans of this
+// vCluster are adopted by recording their mapping, which makes them managed.
+func (a *Adopter) Import(ctx *synccontext.SyncContext, pObj client.Object) (bool, error) {
+	if a.policy != AdoptionAdopt || !a.isOrphanCandidate(ctx, pObj) {
+		return false, nil
+	}
+
+	vName := virtualNameFromAnnotations(pObj)
+	if _, ok := ctx.Mappings.Store().VirtualToHostName(ctx, synccontext.Object{GroupVersionKind: a.gvk, NamespacedName: vName}); ok {
+		// the virtual object is mapped to another host object already
+		return false, nil
+	}
+
+	vObj, err := a.getVirtualObject(ctx, vName)
+	if err != nil {
+		return false, err
+	} else if vObj != nil && string(vObj.GetUID()) == pObj.GetAnnotations()[translate.UIDAnnotation] {
+		return false, nil
+	}
+
+	mapping := synccontext.NameMapping{
+		GroupVersionKind: a.gvk,
+		VirtualName:      vName,
+		HostName:         types.NamespacedName{Namespace: pObj.GetNamespace(), Name: pObj.GetName()},
+	}
+	err = ctx.Mappings.Store().AddReferenceAndSave(ctx, mapping, mapping)
+	if err != nil {
+		return false, fmt.Errorf("record mapping %s: %w", mapping.String(), err)
+	}
+
+	ctx.Log.Infof("adopt %s %s as %s", a.gvk.Kind, mapping.HostName.String(), vName.String())
+	return true, nil
+}
+
+// IgnoreHostObject never ignores host objects, orphans are handled by HandleOrphan where
+// deleted virtual objects can be told apart from orphans
+func (a *Adopter) IgnoreHostObject(_ *synccontext.SyncContext, _ client.Object) bool {
+	return false
+}
+
+// HandleOrphan handles a host object without a virtual object according to the policy and
+// returns true if it did. It needs to be called by SyncToVirtual, the caller deletes the host
+// object if it wasn't handled.
+func (a *Adopter) HandleOrphan(ctx *synccontext.SyncContext, pObj, vObjOld client.Object, hasStatusSubresource bool) (ctrl.Result, bool, error) {
+	if a.policy == AdoptionDelete || !clienthelper.IsNilObject(vObjOld) || !a.isOrphanCandidate(ctx, pObj) {
+		return ctrl.Result{}, false, nil
+	} else if a.policy == AdoptionIgnore {
+		ctx.Log.Debugf("ignore orphaned %s %s", a.gvk.Kind, pObj.GetName())
+		return ctrl.Result{}, true, nil
+	}
+
+	vName := virtualNameFromAnnotations(pObj)
+	if vName.Namespace != "" {
+		result, ready, err := ensureVirtualNamespace(ctx, vName.Namespace)
+		if !ready || err != nil {
+			return result, true, err
+		}
+	}
+
+	vObj := translate.VirtualMetadata(pObj, vName)
+	result, err := patcher.CreateVirtualObject(ctx, pObj, vObj, a.eventRecorder, hasStatusSubresource)
+	if err != nil {
+		return result, true, err
+	}
+
+	return result, true, a.Takeover(ctx, vObj, pObj)
+}
+
+// Takeover moves a host object that was synced from a previous virtual object of the same
+// name to the given virtual object by updating its uid annotation. It needs to be called by
+// Sync before any changes are made to the host object.
+func (a *Adopter) Takeover(ctx *synccontext.SyncContext, vObj, pObj client.Object) error {
+	uid := pObj.GetAnnotations()[translate.UIDAnnotation]
+	if a.policy != AdoptionAdopt || uid == "" || uid == string(vObj.GetUID()) {
+		return nil
+	}
+
+	patch := client.MergeFrom(pObj.DeepCopyObject().(client.Object))
+	annotations := pObj.GetAnnotations()
+	annotations[translate.UIDAnnotation] = string(vObj.GetUID())
+	pObj.SetAnnotations(annotations)
+	err := ctx.HostClient.Patch(ctx, pObj, patch)
+	if err != nil {
+		return fmt.Errorf("update uid of host object %s: %w", pObj.GetName(), err)
+	}
+
+	ctx.Log.Infof("adopted host %s %s previously synced from uid %s", a.gvk.Kind, pObj.GetName(), uid)
+	if a.eventRecorder != nil {
+		a.eventRecorder.Eventf(vObj, pObj, "Normal", "Adopted", "Adopt", "Adopted host object %s previously synced from uid %s", pObj.GetName(), uid)
+	}
+	return nil
+}
+
+// isOrphanCandidate checks through the annotations if the host object was synced from an
+// object of this kind by a vCluster with the same name
+func (a *Adopter) isOrphanCandidate(ctx *synccontext.SyncContext, pObj client.Object) bool {
+	if clienthelper.IsNilObject(pObj) || pObj.GetDeletionTimestamp() != nil {
+		return false
+	}
+
+	annotations := pObj.GetAnnotations()
+	if annotations[translate.NameAnnotation] == "" {
+		return false
+	} else if kind := annotations[translate.KindAnnotation]; kind != "" && kind != a.gvk.String() {
+		return false
+	} else if hostName := annotations[translate.HostNameAnnotation]; hostName != "" && hostName != pObj.GetName() {
+		return false
+	}
+
+	// objects of other vClusters are never adopted
+	marker, ok := pObj.GetLabels()[translate.MarkerLabel]
+	if pObj.GetNamespace() == "" {
+		return marker == translate.Default.MarkerLabelCluster()
+	} else if ok && marker != translate.VClusterName {
+		return false
+	}
+
+	return translate.Default.IsTargetedNamespace(ctx, pObj.GetNamespace())
+}
+
+func (a *Adopter) getVirtualObject(ctx *synccontext.SyncContext, vName types.NamespacedName) (client.Object, error) {
+	vObj := &unstructured.Unstructured{}
+	vObj.SetGroupVersionKind(a.gvk)
+	err := ctx.VirtualClient.Get(ctx, vName, vObj)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get virtual object %s: %w", vName.String(), err)
+	}
+
+	return vObj, nil
+}
+
+func virtualNameFromAnnotations(pObj client.Object) types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: pObj.GetAnnotations()[translate.NamespaceAnnotation],
+		Name:      pObj.GetAnnotations()[translate.NameAnnotation],
+	}
+}
@@ -0,0 +1,391 @@
+package syncers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/log"
+	"github.com/loft-sh/vcluster/pkg/constants"
+	"github.com/loft-sh/vcluster/pkg/helm"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
+	"github.com/loft-sh/vcluster/pkg/util/kubeconfig"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/klog/v2"
+)
+
+// ChartStatus is the state of a chart release
+type ChartStatus string
+
+const (
+	// ChartPending means the release wasn't installed, upgraded or uninstalled yet
+	ChartPending ChartStatus = "Pending"
+
+	// ChartDeployed means the release is installed with the current chart and values
+	ChartDeployed ChartStatus = "Deployed"
+
+	// ChartUninstalled means the release was uninstalled
+	ChartUninstalled ChartStatus = "Uninstalled"
+
+	// ChartFailed means the last install, upgrade or uninstall failed, it's retried with
+	// a backoff
+	ChartFailed ChartStatus = "Failed"
+)
+
+const (
+	chartHashKey    = "hash"
+	chartStatusKey  = "status"
+	chartMessageKey = "message"
+)
+
+// ChartOptions configure a helm chart installed into the virtual cluster
+type ChartOptions struct {
+	// Name is the name of the release
+	Name string
+
+	// Namespace is the virtual namespace of the release, it's created if it doesn't exist.
+	// Defaults to default.
+	Namespace string
+
+	// Path is a local chart directory or chart archive, e.g. copied into the plugin image
+	Path string
+
+	// Archive is a chart archive (.tgz), e.g. embedded into the plugin binary. It's used
+	// if Path is empty.
+	Archive []byte
+
+	// Values are the values of the release, e.g. taken from the plugin config
+	Values interface{}
+
+	// Uninstall uninstalls the release instead, e.g. if the chart was disabled in the
+	// plugin config
+	Uninstall bool
+
+	// Timeout is the timeout of a single helm install, upgrade or uninstall. Defaults to
+	// 5 minutes.
+	Timeout time.Duration
+
+	// HelmBinary is the path of the helm binary, defaults to the one shipped with vCluster
+	HelmBinary string
+}
+
+// NewChart creates a syncer that installs, upgrades or uninstalls a helm chart in the
+// virtual cluster. The chart is installed in the background as soon as the plugin became
+// leader and only upgraded if the chart or values changed since the last run. Failed runs
+// are rolled back and retried. The state of the release is stored in a config map in the
+// virtual kube-system namespace, which also receives events for each run, and a failed
+// release is reported through the health endpoint, see plugin.HealthChecker.
+func NewChart(options ChartOptions) (syncertypes.Base, error) {
+	if options.Name == "" {
+		return nil, errors.New("chart release name is empty")
+	} else if options.Path == "" && len(options.Archive) == 0 {
+		return nil, fmt.Errorf("chart %s: either path or archive is required", options.Name)
+	}
+
+	if options.Namespace == "" {
+		options.Namespace = metav1.NamespaceDefault
+	}
+	if options.Timeout == 0 {
+		options.Timeout = 5 * time.Minute
+	}
+	if options.HelmBinary == "" {
+		options.HelmBinary = constants.HelmBinary
+	}
+
+	values := ""
+	if options.Values != nil {
+		rawValues, err := yaml.Marshal(options.Values)
+		if err != nil {
+			return nil, fmt.Errorf("marshal values of chart %s: %w", options.Name, err)
+		}
+		values = string(rawValues)
+	}
+
+	return &chartSyncer{
+		options: options,
+		values:  values,
+		status:  ChartPending,
+	}, nil
+}
+
+type chartSyncer struct {
+	options ChartOptions
+	values  string
+
+	helmClient    helm.Client
+	virtualClient kubernetes.Interface
+	eventRecorder events.EventRecorder
+
+	m       sync.Mutex
+	status  ChartStatus
+	message string
+}
+
+var _ syncertypes.ControllerStarter = &chartSyncer{}
+
+func (s *chartSyncer) Name() string {
+	return "chart-" + s.options.Name
+}
+
+// Check returns an error if the last run failed, see plugin.HealthChecker
+func (s *chartSyncer) Check(_ *http.Request) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.status == ChartFailed {
+		return fmt.Errorf("release %s/%s failed: %s", s.options.Namespace, s.options.Name, s.message)
+	}
+
+	return nil
+}
+
+func (s *chartSyncer) Register(ctx *synccontext.RegisterContext) error {
+	clientConfig, err := kubeconfig.ConvertRestConfigToClientConfig(ctx.VirtualManager.GetConfig())
+	if err != nil {
+		return fmt.Errorf("convert virtual config: %w", err)
+	}
+	rawConfig, err := clientConfig.RawConfig()
+	if err != nil {
+		return fmt.Errorf("convert virtual config: %w", err)
+	}
+	s.helmClient = helm.NewClient(&rawConfig, log.GetInstance(), s.options.HelmBinary)
+
+	s.virtualClient, err = kubernetes.NewForConfig(ctx.VirtualManager.GetConfig())
+	if err != nil {
+		return fmt.Errorf("create virtual client: %w", err)
+	}
+	s.eventRecorder = ctx.VirtualManager.GetEventRecorder(s.Name())
+
+	// helm runs can take a while, so don't block the other syncers
+	go func() {
+		backoff := wait.Backoff{Duration: 10 * time.Second, Factor: 2, Jitter: 0.1, Steps: 6, Cap: 5 * time.Minute}
+		for {
+			err := s.run(ctx)
+			if err == nil {
+				return
+			}
+
+			klog.FromContext(ctx).Error(err, "Chart run failed", "release", s.options.Name)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff.Step()):
+			}
+		}
+	}()
+
+	return nil
+}
+
+// run installs, upgrades or uninstalls the release and records the result
+func (s *chartSyncer) run(ctx context.Context) error {
+	configMap, err := s.getStatusConfigMap(ctx)
+	if err != nil {
+		return err
+	}
+
+	if s.options.Uninstall {
+		err = s.uninstall(ctx)
+		if err != nil {
+			return s.recordStatus(ctx, configMap, "", ChartFailed, "UninstallFailed", err)
+		}
+
+		return s.recordStatus(ctx, configMap, "", ChartUninstalled, "Uninstalled", nil)
+	}
+
+	chartPath, cleanup, err := s.chartPath()
+	if err != nil {
+		return s.recordStatus(ctx, configMap, "", ChartFailed, "InstallFailed", err)
+	}
+	defer cleanup()
+
+	hash, err := s.hash(chartPath)
+	if err != nil {
+		return s.recordStatus(ctx, configMap, "", ChartFailed, "InstallFailed", err)
+	}
+
+	exists, err := s.helmClient.Exists(s.options.Name, s.options.Namespace)
+	if err != nil {
+		return err
+	} else if exists && configMap.Data[chartHashKey] == hash && ChartStatus(configMap.Data[chartStatusKey]) == ChartDeployed {
+		klog.FromContext(ctx).Info("Chart release is up to date", "release", s.options.Name, "namespace", s.options.Namespace)
+		s.setStatus(ChartDeployed, "")
+		return nil
+	}
+
+	reason, failedReason := "Installed", "InstallFailed"
+	if exists {
+		reason, failedReason = "Upgraded", "UpgradeFailed"
+	}
+	err = s.upgrade(ctx, chartPath)
+	if err != nil {
+		return s.recordStatus(ctx, configMap, "", ChartFailed, failedReason, err)
+	}
+
+	return s.recordStatus(ctx, configMap, hash, ChartDeployed, reason, nil)
+}
+
+func (s *chartSyncer) upgrade(ctx context.Context, chartPath string) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, s.options.Timeout)
+	defer cancel()
+
+	// atomic makes helm roll back or uninstall the release if the run fails
+	klog.FromContext(ctx).Info("Install chart release", "release", s.options.Name, "namespace", s.options.Namespace)
+	return s.helmClient.Upgrade(timeoutCtx, s.options.Name, s.options.Namespace, helm.UpgradeOptions{
+		Chart:           s.options.Name,
+		Path:            chartPath,
+		Values:          s.values,
+		CreateNamespace: true,
+		Atomic:          true,
+	})
+}
+
+func (s *chartSyncer) uninstall(ctx context.Context) error {
+	exists, err := s.helmClient.Exists(s.options.Name, s.options.Namespace)
+	if err != nil || !exists {
+		return err
+	}
+
+	klog.FromContext(ctx).Info("Uninstall chart release", "release", s.options.Name, "namespace", s.options.Namespace)
+	return s.helmClient.Delete(s.options.Name, s.options.Namespace)
+}
+
+// chartPath returns the path of the chart, an archive is written to a temporary file
+// that is removed by the returned cleanup func
+func (s *chartSyncer) chartPath() (string, func(), error) {
+	if s.options.Path != "" {
+		return s.options.Path, func() {}, nil
+	}
+
+	archive, err := os.CreateTemp("", "chart-*.tgz")
+	if err != nil {
+		return "", nil, fmt.Errorf("create chart archive: %w", err)
+	}
+	cleanup := func() {
+		_ = os.Remove(archive.Name())
+	}
+
+	_, err = archive.Write(s.options.Archive)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("write chart archive: %w", err)
+	}
+
+	return archive.Name(), cleanup, nil
+}
+
+// hash returns a hash of the chart files, values and namespace to detect if an upgrade
+// is needed
+func (s *chartSyncer) hash(chartPath string) (string, error) {
+	hash := sha256.New()
+	_, _ = io.WriteString(hash, s.options.Namespace+"\x00"+s.values+"\x00")
+
+	err := filepath.WalkDir(chartPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		relativePath, err := filepath.Rel(chartPath, path)
+		if err != nil {
+			return err
+		}
+		_, _ = io.WriteString(hash, relativePath+"\x00")
+		_, err = io.Copy(hash, file)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("hash chart %s: %w", chartPath, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// recordStatus stores the result of a run in the status config map, emits an event for
+// it and returns runErr
+func (s *chartSyncer) recordStatus(ctx context.Context, configMap *corev1.ConfigMap, hash string, status ChartStatus, reason string, runErr error) error {
+	message := ""
+	if runErr != nil {
+		message = runErr.Error()
+	}
+	s.setStatus(status, message)
+
+	configMap.Data = map[string]string{
+		chartHashKey:    hash,
+		chartStatusKey:  string(status),
+		chartMessageKey: message,
+	}
+	var err error
+	if configMap.ResourceVersion == "" {
+		configMap, err = s.virtualClient.CoreV1().ConfigMaps(configMap.Namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	} else {
+		configMap, err = s.virtualClient.CoreV1().ConfigMaps(configMap.Namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return errors.Join(runErr, fmt.Errorf("save chart status: %w", err))
+	}
+
+	if runErr != nil {
+		s.eventRecorder.Eventf(configMap, nil, corev1.EventTypeWarning, reason, "Helm", "Release %s/%s failed: %v", s.options.Namespace, s.options.Name, runErr)
+		return runErr
+	}
+
+	note := ""
+	if status == ChartDeployed {
+		release, err := helm.NewSecrets(s.virtualClient).Get(ctx, s.options.Name, s.options.Namespace)
+		if err == nil && release.Info != nil {
+			note = fmt.Sprintf(" (revision %d, %s)", release.Version, release.Info.Status)
+		}
+	}
+	s.eventRecorder.Eventf(configMap, nil, corev1.EventTypeNormal, reason, "Helm", "Release %s/%s %s%s", s.options.Namespace, s.options.Name, string(status), note)
+	return nil
+}
+
+func (s *chartSyncer) getStatusConfigMap(ctx context.Context) (*corev1.ConfigMap, error) {
+	name := "vcluster-plugin-chart-" + s.options.Name
+	configMap, err := s.virtualClient.CoreV1().ConfigMaps(inventoryNamespace).Get(ctx, name, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: inventoryNamespace,
+			},
+		}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get chart status: %w", err)
+	}
+
+	return configMap, nil
+}
+
+func (s *chartSyncer) setStatus(status ChartStatus, message string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.status = status
+	s.message = message
+}
@@ -0,0 +1,438 @@
+package syncers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/vcluster/pkg/mappings/generic"
+	"github.com/loft-sh/vcluster/pkg/patcher"
+	"github.com/loft-sh/vcluster/pkg/syncer"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	"github.com/loft-sh/vcluster/pkg/syncer/translator"
+	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
+	"github.com/loft-sh/vcluster/pkg/util"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsv1clientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterScopedOptions configure how a cluster scoped kind is synced
+type ClusterScopedOptions struct {
+	// CRD is the manifest of the custom resource definition of the kind. When syncing to the
+	// host it is applied in both clusters, when projecting only in the virtual cluster. If
+	// empty, the kind has to exist in the host cluster already and custom resource
+	// definitions are copied into the virtual cluster.
+	CRD []byte
+
+	// Name is the name of the syncer, defaults to the lower case kind
+	Name string
+
+	// HostName translates the virtual name of an object to its host name, defaults to
+	// translate.Default.HostNameCluster, which is unique for every vCluster on the host
+	HostName func(vName string) string
+
+	// Fields define which fields are synced in which direction. Defaults to DefaultCRDFields.
+	Fields []FieldSpec
+
+	// ConflictPolicy decides which side wins if a field synced in both directions changed
+	// in the virtual and the host object, defaults to ConflictVirtualWins
+	ConflictPolicy ConflictPolicy
+
+	// Adoption defines what happens to host objects without a virtual object that were
+	// left by a previous vCluster, defaults to AdoptionDelete. Not used for projections.
+	Adoption AdoptionPolicy
+
+	// ProjectInto reverses the sync. If set, the cluster scoped host objects are imported
+	// read only into namespaced virtual objects of this namespace, so the kind needs to be
+	// namespaced in the virtual cluster. Without a CRD manifest, the host definition is
+	// copied into the virtual cluster as namespaced definition.
+	ProjectInto string
+
+	// LabelSelector restricts the host objects that are projected
+	LabelSelector labels.Selector
+}
+
+// NewClusterScopedSyncer creates a syncer for a cluster scoped kind, such as cluster roles or
+// cluster scoped custom resources. Virtual objects are synced to host objects with a name
+// that is unique for this vCluster and the cluster marker label, so vClusters sharing a host
+// cluster never manage each other's objects. Host objects that already exist under the
+// translated name but belong to another vCluster or to no vCluster at all are never
+// overwritten, a NameCollision event is recorded on the virtual object instead.
+//
+// With ClusterScopedOptions.ProjectInto set, the host objects are projected into namespaced
+// virtual objects of the given namespace instead.
+func NewClusterScopedSyncer(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind, options ClusterScopedOptions) (syncertypes.Syncer, error) {
+	if options.ProjectInto != "" {
+		return newProjectionSyncer(ctx, gvk, options)
+	}
+
+	fieldSpecs := options.Fields
+	if fieldSpecs == nil {
+		fieldSpecs = DefaultCRDFields
+	}
+	fields, err := NewFieldSync(fieldSpecs, options.ConflictPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	hasStatusSubresource, err := ensureClusterScopedKind(ctx, gvk, options.CRD)
+	if err != nil {
+		return nil, fmt.Errorf("ensure %s: %w", gvk.String(), err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+
+	var mapper synccontext.Mapper
+	if ctx.Mappings.Has(gvk) {
+		// reuse the mapper registered through Options.RegisterMappings
+		mapper, err = ctx.Mappings.ByGVK(gvk)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		hostName := options.HostName
+		if hostName == nil {
+			hostName = translate.Default.HostNameCluster
+		}
+
+		mapper, err = generic.NewMapper(ctx, obj, func(_ *synccontext.SyncContext, vName, _ string) types.NamespacedName {
+			return types.NamespacedName{Name: hostName(vName)}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create mapper for %s: %w", gvk.String(), err)
+		}
+
+		err = ctx.Mappings.AddMapper(mapper)
+		if err != nil {
+			return nil, fmt.Errorf("add mapper %s: %w", gvk.String(), err)
+		}
+	}
+
+	name := options.Name
+	if name == "" {
+		name = strings.ToLower(gvk.Kind)
+	}
+
+	genericTranslator := translator.NewGenericTranslator(ctx, name, obj, mapper)
+	adopter, err := NewAdopter(gvk, options.Adoption, genericTranslator.EventRecorder())
+	if err != nil {
+		return nil, err
+	}
+
+	return &clusterScopedSyncer{
+		GenericTranslator: genericTranslator,
+		Adopter:           adopter,
+
+		fields:               fields,
+		hasStatusSubresource: hasStatusSubresource,
+	}, nil
+}
+
+type clusterScopedSyncer struct {
+	syncertypes.GenericTranslator
+	*Adopter
+
+	fields               *FieldSync
+	hasStatusSubresource bool
+}
+
+var _ syncertypes.Syncer = &clusterScopedSyncer{}
+
+var _ syncertypes.OptionsProvider = &clusterScopedSyncer{}
+
+var _ syncertypes.Importer = &clusterScopedSyncer{}
+
+func (s *clusterScopedSyncer) Options() *syncertypes.Options {
+	// the old objects are needed for fields synced in both directions
+	return &syncertypes.Options{
+		ObjectCaching:      true,
+		IsClusterScopedCRD: true,
+		DisableUIDDeletion: s.DisableUIDDeletion(),
+	}
+}
+
+func (s *clusterScopedSyncer) Syncer() syncertypes.Sync[client.Object] {
+	return syncer.ToGenericSyncer[*unstructured.Unstructured](s)
+}
+
+// IsManaged only accepts host objects with the cluster marker label of this vCluster, so
+// objects of other vClusters sharing the host cluster are never synced
+func (s *clusterScopedSyncer) IsManaged(ctx *synccontext.SyncContext, pObj client.Object) (bool, error) {
+	if pObj.GetNamespace() != "" || pObj.GetLabels()[translate.MarkerLabel] != translate.Default.MarkerLabelCluster() {
+		return false, nil
+	}
+
+	return s.GenericTranslator.IsManaged(ctx, pObj)
+}
+
+func (s *clusterScopedSyncer) SyncToHost(ctx *synccontext.SyncContext, event *synccontext.SyncToHostEvent[*unstructured.Unstructured]) (ctrl.Result, error) {
+	pObj := translate.HostMetadata(event.Virtual, s.VirtualToHost(ctx, types.NamespacedName{Name: event.Virtual.GetName()}, event.Virtual))
+	if pObj.GetName() == "" {
+		return ctrl.Result{}, nil
+	}
+
+	// unmanaged host objects are excluded by the syncer, so check if the name is taken
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(s.GroupVersionKind())
+	err := ctx.HostClient.Get(ctx, types.NamespacedName{Name: pObj.GetName()}, existing)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("get host object %s: %w", pObj.GetName(), err)
+	} else if err == nil {
+		if reason := s.collision(event.Virtual, existing); reason != "" {
+			return s.reportCollision(ctx, event.Virtual, existing, reason)
+		}
+	}
+
+	// fields that are owned by the host are not copied down
+	err = s.fields.RemoveHostOwned(pObj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return patcher.CreateHostObject(ctx, event.Virtual, pObj, s.EventRecorder(), s.hasStatusSubresource)
+}
+
+func (s *clusterScopedSyncer) Sync(ctx *synccontext.SyncContext, event *synccontext.SyncEvent[*unstructured.Unstructured]) (ctrl.Result, error) {
+	if reason := s.collision(event.Virtual, event.Host); reason != "" {
+		return s.reportCollision(ctx, event.Virtual, event.Host, reason)
+	}
+	err := s.Takeover(ctx, event.Virtual, event.Host)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var options []patcher.Option
+	if !s.hasStatusSubresource {
+		options = append(options, patcher.NoStatusSubResource())
+	}
+
+	return SyncFields(ctx, event, s.fields, s.EventRecorder(), options...)
+}
+
+func (s *clusterScopedSyncer) SyncToVirtual(ctx *synccontext.SyncContext, event *synccontext.SyncToVirtualEvent[*unstructured.Unstructured]) (ctrl.Result, error) {
+	result, handled, err := s.HandleOrphan(ctx, event.Host, event.VirtualOld, s.hasStatusSubresource)
+	if handled {
+		return result, err
+	}
+
+	// virtual object is not here anymore, so we delete
+	return patcher.DeleteHostObject(ctx, event.Host, event.VirtualOld, "virtual object was deleted")
+}
+
+// collision returns why the host object can't be used for the virtual object or an empty
+// string if it belongs to the virtual object
+func (s *clusterScopedSyncer) collision(vObj, pObj client.Object) string {
+	marker, ok := pObj.GetLabels()[translate.MarkerLabel]
+	switch {
+	case !ok:
+		return "is not managed by vCluster"
+	case marker != translate.Default.MarkerLabelCluster():
+		return fmt.Sprintf("is managed by another vCluster (%s=%s)", translate.MarkerLabel, marker)
+	case pObj.GetAnnotations()[translate.NameAnnotation] != vObj.GetName():
+		return fmt.Sprintf("belongs to virtual object %s", pObj.GetAnnotations()[translate.NameAnnotation])
+	}
+
+	return ""
+}
+
+// reportCollision records a warning on the virtual object. The virtual object is synced
+// again as soon as it changes.
+func (s *clusterScopedSyncer) reportCollision(ctx *synccontext.SyncContext, vObj, pObj client.Object, reason string) (ctrl.Result, error) {
+	ctx.Log.Infof("cannot sync %s %s to host, host object %s %s", s.GroupVersionKind().Kind, vObj.GetName(), pObj.GetName(), reason)
+	s.EventRecorder().Eventf(vObj, nil, "Warning", "NameCollision", "SyncToHost", "Host object %s %s", pObj.GetName(), reason)
+	return ctrl.Result{}, nil
+}
+
+// newProjectionSyncer creates a read only import of cluster scoped host objects into
+// namespaced virtual objects
+func newProjectionSyncer(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind, options ClusterScopedOptions) (syncertypes.Syncer, error) {
+	if errs := validation.IsDNS1123Label(options.ProjectInto); len(errs) > 0 {
+		return nil, fmt.Errorf("namespace %s is invalid: %s", options.ProjectInto, strings.Join(errs, ", "))
+	}
+
+	labelSelector := options.LabelSelector
+	if labelSelector == nil {
+		labelSelector = labels.Everything()
+	}
+
+	hasStatusSubresource, err := ensureProjectedKind(ctx, gvk, options.CRD)
+	if err != nil {
+		return nil, fmt.Errorf("ensure %s: %w", gvk.String(), err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+
+	name := options.Name
+	if name == "" {
+		name = strings.ToLower(gvk.Kind)
+	}
+
+	return &fromHostSyncer{
+		GenericTranslator: translator.NewGenericTranslator(ctx, name, obj, &projectionMapper{
+			gvk:       gvk,
+			namespace: options.ProjectInto,
+		}),
+
+		name:                 name,
+		labelSelector:        labelSelector,
+		mode:                 ImportReadOnly,
+		hasStatusSubresource: hasStatusSubresource,
+	}, nil
+}
+
+// projectionMapper maps cluster scoped host objects to virtual objects of the same name in
+// a single namespace
+type projectionMapper struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+}
+
+var _ synccontext.Mapper = &projectionMapper{}
+
+func (p *projectionMapper) GroupVersionKind() schema.GroupVersionKind {
+	return p.gvk
+}
+
+func (p *projectionMapper) Migrate(_ *synccontext.RegisterContext, _ synccontext.Mapper) error {
+	return nil
+}
+
+func (p *projectionMapper) VirtualToHost(_ *synccontext.SyncContext, req types.NamespacedName, _ client.Object) types.NamespacedName {
+	if req.Namespace != p.namespace {
+		return types.NamespacedName{}
+	}
+
+	return types.NamespacedName{Name: req.Name}
+}
+
+func (p *projectionMapper) HostToVirtual(_ *synccontext.SyncContext, req types.NamespacedName, _ client.Object) types.NamespacedName {
+	if req.Namespace != "" {
+		return types.NamespacedName{}
+	}
+
+	return types.NamespacedName{Namespace: p.namespace, Name: req.Name}
+}
+
+func (p *projectionMapper) IsManaged(_ *synccontext.SyncContext, pObj client.Object) (bool, error) {
+	return pObj.GetNamespace() == "", nil
+}
+
+// ensureClusterScopedKind makes sure the kind is cluster scoped and served in both clusters
+// and returns if it has a status subresource
+func ensureClusterScopedKind(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind, manifest []byte) (bool, error) {
+	if len(manifest) > 0 {
+		// check the scope before the crd is applied, so a namespaced crd is never installed
+		isClusterScoped, err := crdIsClusterScoped(ctx, gvk, manifest)
+		if err != nil {
+			return false, err
+		} else if !isClusterScoped {
+			return false, fmt.Errorf("crd manifest defines a namespaced resource")
+		}
+
+		_, hasStatusSubresource, err := ensureCRD(ctx, gvk, manifest, nil)
+		return hasStatusSubresource, err
+	}
+
+	hostMapping, err := ctx.HostManager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, fmt.Errorf("host cluster: %w", err)
+	} else if hostMapping.Scope.Name() != meta.RESTScopeNameRoot {
+		return false, fmt.Errorf("%s is namespaced in the host cluster", gvk.String())
+	}
+
+	mapping, err := ctx.VirtualManager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		_, hasStatusSubresource, err := translate.EnsureCRDFromPhysicalCluster(ctx.Context, ctx.HostManager.GetConfig(), ctx.VirtualManager.GetConfig(), gvk)
+		return hasStatusSubresource, err
+	} else if err != nil {
+		return false, err
+	} else if mapping.Scope.Name() != meta.RESTScopeNameRoot {
+		return false, fmt.Errorf("%s is namespaced in the virtual cluster", gvk.String())
+	}
+
+	return hasStatusSubresource(ctx, mapping.Resource)
+}
+
+// ensureProjectedKind makes sure the kind is cluster scoped in the host cluster and
+// namespaced in the virtual cluster and returns if it has a status subresource in the
+// virtual cluster
+func ensureProjectedKind(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind, manifest []byte) (bool, error) {
+	hostMapping, err := ctx.HostManager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, fmt.Errorf("host cluster: %w", err)
+	} else if hostMapping.Scope.Name() != meta.RESTScopeNameRoot {
+		return false, fmt.Errorf("%s is namespaced in the host cluster", gvk.String())
+	}
+
+	mapping, err := ctx.VirtualManager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err == nil {
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			return false, fmt.Errorf("%s is cluster scoped in the virtual cluster", gvk.String())
+		}
+
+		return hasStatusSubresource(ctx, mapping.Resource)
+	} else if !meta.IsNoMatchError(err) {
+		return false, err
+	}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if len(manifest) > 0 {
+		err = yaml.Unmarshal(manifest, crd)
+		if err != nil {
+			return false, fmt.Errorf("parse crd manifest: %w", err)
+		} else if crd.Spec.Scope != apiextensionsv1.NamespaceScoped {
+			return false, fmt.Errorf("crd manifest defines a cluster scoped resource")
+		}
+	} else {
+		hostClient, err := apiextensionsv1clientset.NewForConfig(ctx.HostManager.GetConfig())
+		if err != nil {
+			return false, err
+		}
+
+		hostCRD, err := hostClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx.Context, hostMapping.Resource.GroupResource().String(), metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("retrieve crd in host cluster: %w", err)
+		}
+
+		// conversion webhooks of the host cluster aren't reachable from the virtual cluster
+		crd.APIVersion = apiextensionsv1.SchemeGroupVersion.String()
+		crd.Kind = "CustomResourceDefinition"
+		crd.Name = hostCRD.Name
+		crd.Spec = hostCRD.Spec
+		crd.Spec.Scope = apiextensionsv1.NamespaceScoped
+		crd.Spec.Conversion = nil
+		manifest, err = yaml.Marshal(crd)
+		if err != nil {
+			return false, fmt.Errorf("marshal crd: %w", err)
+		}
+	}
+
+	version := slices.IndexFunc(crd.Spec.Versions, func(version apiextensionsv1.CustomResourceDefinitionVersion) bool {
+		return version.Name == gvk.Version
+	})
+	if crd.Spec.Group != gvk.Group || crd.Spec.Names.Kind != gvk.Kind || version == -1 {
+		return false, fmt.Errorf("crd %s does not define %s", crd.Name, gvk.String())
+	}
+
+	err = util.EnsureCRD(ctx.Context, ctx.VirtualManager.GetConfig(), manifest, gvk)
+	if err != nil {
+		return false, fmt.Errorf("virtual cluster: %w", err)
+	}
+
+	subresources := crd.Spec.Versions[version].Subresources
+	return subresources != nil && subresources.Status != nil, nil
+}
@@ -0,0 +1,369 @@
+package syncers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConvertFunc converts a custom resource in place. The apiVersion is already set to the
+// target version when the func is called.
+type ConvertFunc func(obj *unstructured.Unstructured) error
+
+// Conversion converts custom resources between the version served in the virtual cluster and
+// a different version served in the host cluster, e.g. after the host operator was upgraded
+type Conversion struct {
+	// HostVersion is the version the host cluster serves
+	HostVersion string
+
+	// ToHost converts a virtual object to the host version. If nil, only the apiVersion
+	// is changed.
+	ToHost ConvertFunc
+
+	// ToVirtual converts a host object to the virtual version. If nil, only the apiVersion
+	// is changed.
+	ToVirtual ConvertFunc
+}
+
+// converter converts the objects of a kind between the virtual and the host version
+type converter struct {
+	virtualGVK schema.GroupVersionKind
+	hostGVK    schema.GroupVersionKind
+	conversion Conversion
+}
+
+func newConverter(gvk schema.GroupVersionKind, conversion Conversion) (*converter, error) {
+	if conversion.HostVersion == "" {
+		return nil, fmt.Errorf("conversion of %s: host version is empty", gvk.String())
+	}
+
+	return &converter{
+		virtualGVK: gvk,
+		hostGVK:    schema.GroupVersionKind{Group: gvk.Group, Version: conversion.HostVersion, Kind: gvk.Kind},
+		conversion: conversion,
+	}, nil
+}
+
+// matches returns if obj is an object of the converted kind in the virtual version
+func (c *converter) matches(obj interface{ GetObjectKind() schema.ObjectKind }) bool {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	return gvk == c.virtualGVK || gvk == c.virtualGVK.GroupVersion().WithKind(c.virtualGVK.Kind+"List")
+}
+
+func (c *converter) toHost(vObj client.Object) (*unstructured.Unstructured, error) {
+	return c.convert(vObj, c.hostGVK, c.conversion.ToHost)
+}
+
+func (c *converter) toVirtual(pObj client.Object) (*unstructured.Unstructured, error) {
+	return c.convert(pObj, c.virtualGVK, c.conversion.ToVirtual)
+}
+
+func (c *converter) convert(obj client.Object, gvk schema.GroupVersionKind, convertFunc ConvertFunc) (*unstructured.Unstructured, error) {
+	unstructuredObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("conversion of %s needs an unstructured object, got %T", c.virtualGVK.String(), obj)
+	}
+
+	converted := unstructuredObj.DeepCopy()
+	converted.SetGroupVersionKind(gvk)
+	if convertFunc != nil {
+		err := convertFunc(converted)
+		if err != nil {
+			return nil, fmt.Errorf("convert %s %s to %s: %w", c.virtualGVK.Kind, obj.GetName(), gvk.Version, err)
+		}
+	}
+
+	// conversion funcs must not change the identity of the object
+	converted.SetGroupVersionKind(gvk)
+	converted.SetNamespace(obj.GetNamespace())
+	converted.SetName(obj.GetName())
+	return converted, nil
+}
+
+// into converts obj with the conversion func and copies the result into target
+func (c *converter) into(obj, target client.Object, convertFunc func(client.Object) (*unstructured.Unstructured, error)) error {
+	converted, err := convertFunc(obj)
+	if err != nil {
+		return err
+	}
+
+	target.(*unstructured.Unstructured).Object = converted.Object
+	return nil
+}
+
+func (c *converter) get(ctx context.Context, reader client.Reader, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	pObj := &unstructured.Unstructured{}
+	pObj.SetGroupVersionKind(c.hostGVK)
+	err := reader.Get(ctx, key, pObj, opts...)
+	if err != nil {
+		return err
+	}
+
+	return c.into(pObj, obj, c.toVirtual)
+}
+
+func (c *converter) list(ctx context.Context, reader client.Reader, list client.ObjectList, opts ...client.ListOption) error {
+	pList := &unstructured.UnstructuredList{}
+	pList.SetGroupVersionKind(c.hostGVK.GroupVersion().WithKind(c.hostGVK.Kind + "List"))
+	err := reader.List(ctx, pList, opts...)
+	if err != nil {
+		return err
+	}
+
+	items := make([]unstructured.Unstructured, 0, len(pList.Items))
+	for i := range pList.Items {
+		vObj, err := c.toVirtual(&pList.Items[i])
+		if err != nil {
+			return err
+		}
+
+		items = append(items, *vObj)
+	}
+
+	vList, ok := list.(*unstructured.UnstructuredList)
+	if !ok {
+		return fmt.Errorf("conversion of %s needs an unstructured list, got %T", c.virtualGVK.String(), list)
+	}
+	vList.SetResourceVersion(pList.GetResourceVersion())
+	vList.SetContinue(pList.GetContinue())
+	vList.Items = items
+	return nil
+}
+
+// withConversion returns a register context whose host manager converts the objects of the
+// kind, so the syncer only sees objects in the virtual version
+func (c *converter) withConversion(ctx *synccontext.RegisterContext) *synccontext.RegisterContext {
+	newCtx := *ctx
+	newCtx.HostManager = &conversionManager{
+		Manager:   ctx.HostManager,
+		converter: c,
+	}
+
+	return &newCtx
+}
+
+type conversionManager struct {
+	ctrl.Manager
+
+	converter *converter
+}
+
+func (m *conversionManager) GetClient() client.Client {
+	return &conversionClient{Client: m.Manager.GetClient(), converter: m.converter}
+}
+
+func (m *conversionManager) GetCache() cache.Cache {
+	return &conversionCache{Cache: m.Manager.GetCache(), converter: m.converter}
+}
+
+var _ cache.Cache = &conversionCache{}
+
+type conversionCache struct {
+	cache.Cache
+
+	converter *converter
+}
+
+func (c *conversionCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if !c.converter.matches(obj) {
+		return c.Cache.Get(ctx, key, obj, opts...)
+	}
+
+	return c.converter.get(ctx, c.Cache, key, obj, opts...)
+}
+
+func (c *conversionCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if !c.converter.matches(list) {
+		return c.Cache.List(ctx, list, opts...)
+	}
+
+	return c.converter.list(ctx, c.Cache, list, opts...)
+}
+
+// GetInformer returns the informer of the host version. Event handlers receive host objects,
+// which is fine for the syncer as it only looks at their metadata.
+func (c *conversionCache) GetInformer(ctx context.Context, obj client.Object, opts ...cache.InformerGetOption) (cache.Informer, error) {
+	if !c.converter.matches(obj) {
+		return c.Cache.GetInformer(ctx, obj, opts...)
+	}
+
+	pObj := &unstructured.Unstructured{}
+	pObj.SetGroupVersionKind(c.converter.hostGVK)
+	return c.Cache.GetInformer(ctx, pObj, opts...)
+}
+
+var _ client.Client = &conversionClient{}
+
+type conversionClient struct {
+	client.Client
+
+	converter *converter
+}
+
+func (c *conversionClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if !c.converter.matches(obj) {
+		return c.Client.Get(ctx, key, obj, opts...)
+	}
+
+	return c.converter.get(ctx, c.Client, key, obj, opts...)
+}
+
+func (c *conversionClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if !c.converter.matches(list) {
+		return c.Client.List(ctx, list, opts...)
+	}
+
+	return c.converter.list(ctx, c.Client, list, opts...)
+}
+
+func (c *conversionClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if !c.converter.matches(obj) {
+		return c.Client.Create(ctx, obj, opts...)
+	}
+
+	pObj, err := c.converter.toHost(obj)
+	if err != nil {
+		return err
+	}
+
+	err = c.Client.Create(ctx, pObj, opts...)
+	if err != nil {
+		return err
+	}
+
+	return c.converter.into(pObj, obj, c.converter.toVirtual)
+}
+
+func (c *conversionClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if !c.converter.matches(obj) {
+		return c.Client.Update(ctx, obj, opts...)
+	}
+
+	pObj, err := c.converter.toHost(obj)
+	if err != nil {
+		return err
+	}
+
+	err = c.Client.Update(ctx, pObj, opts...)
+	if err != nil {
+		return err
+	}
+
+	return c.converter.into(pObj, obj, c.converter.toVirtual)
+}
+
+// Patch applies the patch to the virtual version of the current host object and updates
+// the host object with the result, as patches can't be converted
+func (c *conversionClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if !c.converter.matches(obj) {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	patched, err := c.converter.patch(ctx, c.Client, obj, patch)
+	if err != nil {
+		return err
+	}
+
+	patchOptions := (&client.PatchOptions{}).ApplyOptions(opts)
+	return c.Update(ctx, patched, &client.UpdateOptions{DryRun: patchOptions.DryRun, FieldManager: patchOptions.FieldManager})
+}
+
+func (c *conversionClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if !c.converter.matches(obj) {
+		return c.Client.Delete(ctx, obj, opts...)
+	}
+
+	pObj := &unstructured.Unstructured{}
+	pObj.SetGroupVersionKind(c.converter.hostGVK)
+	pObj.SetNamespace(obj.GetNamespace())
+	pObj.SetName(obj.GetName())
+	return c.Client.Delete(ctx, pObj, opts...)
+}
+
+func (c *conversionClient) Status() client.SubResourceWriter {
+	return &conversionStatusWriter{SubResourceWriter: c.Client.Status(), client: c}
+}
+
+type conversionStatusWriter struct {
+	client.SubResourceWriter
+
+	client *conversionClient
+}
+
+func (w *conversionStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	if !w.client.converter.matches(obj) {
+		return w.SubResourceWriter.Update(ctx, obj, opts...)
+	}
+
+	pObj, err := w.client.converter.toHost(obj)
+	if err != nil {
+		return err
+	}
+
+	err = w.SubResourceWriter.Update(ctx, pObj, opts...)
+	if err != nil {
+		return err
+	}
+
+	return w.client.converter.into(pObj, obj, w.client.converter.toVirtual)
+}
+
+func (w *conversionStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if !w.client.converter.matches(obj) {
+		return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+	}
+
+	patched, err := w.client.converter.patch(ctx, w.client.Client, obj, patch)
+	if err != nil {
+		return err
+	}
+
+	return w.Update(ctx, patched)
+}
+
+// patch applies a merge patch to the virtual version of the current host object
+func (c *converter) patch(ctx context.Context, reader client.Reader, obj client.Object, patch client.Patch) (client.Object, error) {
+	if patch.Type() != types.MergePatchType {
+		return nil, fmt.Errorf("patch type %s is not supported for converted %s", patch.Type(), c.virtualGVK.String())
+	}
+
+	patchData, err := patch.Data(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(c.virtualGVK)
+	err = c.get(ctx, reader, client.ObjectKeyFromObject(obj), current)
+	if err != nil {
+		return nil, err
+	}
+
+	currentData, err := json.Marshal(current.Object)
+	if err != nil {
+		return nil, err
+	}
+	patchedData, err := jsonpatch.MergePatch(currentData, patchData)
+	if err != nil {
+		return nil, fmt.Errorf("apply patch: %w", err)
+	}
+
+	// decode like the api machinery, so numbers stay integers
+	patched := obj.(*unstructured.Unstructured)
+	patched.Object = nil
+	err = patched.UnmarshalJSON(patchedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
@@ -0,0 +1,116 @@
+package syncers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestConverterPatch(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v2", Kind: "Example"}
+	hostObject := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Example",
+		"metadata":   map[string]interface{}{"name": "test", "namespace": "default"},
+		"spec":       map[string]interface{}{"size": int64(1), "image": "nginx"},
+	}}
+
+	// v1 calls the field size, v2 replicas
+	c, err := newConverter(gvk, Conversion{
+		HostVersion: "v1",
+		ToVirtual: func(obj *unstructured.Unstructured) error {
+			size, _, _ := unstructured.NestedFieldCopy(obj.Object, "spec", "size")
+			unstructured.RemoveNestedField(obj.Object, "spec", "size")
+			return unstructured.SetNestedField(obj.Object, size, "spec", "replicas")
+		},
+	})
+	if err != nil {
+		t.Fatalf("new converter: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		patch    client.Patch
+		expected map[string]interface{}
+		err      string
+	}{
+		{
+			name:  "merge patch",
+			patch: client.RawPatch(types.MergePatchType, []byte(`{"spec":{"replicas":3}}`)),
+			expected: map[string]interface{}{
+				"apiVersion": "example.com/v2",
+				"kind":       "Example",
+				"metadata":   map[string]interface{}{"name": "test", "namespace": "default"},
+				"spec":       map[string]interface{}{"replicas": int64(3), "image": "nginx"},
+			},
+		},
+		{
+			name:  "merge patch removing a field",
+			patch: client.RawPatch(types.MergePatchType, []byte(`{"spec":{"image":null}}`)),
+			expected: map[string]interface{}{
+				"apiVersion": "example.com/v2",
+				"kind":       "Example",
+				"metadata":   map[string]interface{}{"name": "test", "namespace": "default"},
+				"spec":       map[string]interface{}{"replicas": int64(1)},
+			},
+		},
+		{
+			name:  "json patch",
+			patch: client.RawPatch(types.JSONPatchType, []byte(`[{"op":"replace","path":"/spec/replicas","value":3}]`)),
+			err:   "not supported",
+		},
+		{
+			name:  "strategic merge patch",
+			patch: client.RawPatch(types.StrategicMergePatchType, []byte(`{"spec":{"replicas":3}}`)),
+			err:   "not supported",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(gvk)
+			obj.SetNamespace("default")
+			obj.SetName("test")
+
+			patched, err := c.patch(context.Background(), &objectReader{obj: hostObject}, obj, testCase.patch)
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if !reflect.DeepEqual(patched.(*unstructured.Unstructured).Object, testCase.expected) {
+				t.Fatalf("expected %v, got %v", testCase.expected, patched.(*unstructured.Unstructured).Object)
+			}
+		})
+	}
+}
+
+// objectReader returns a copy of obj for gets of its kind and key
+type objectReader struct {
+	obj *unstructured.Unstructured
+}
+
+func (r *objectReader) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	if obj.GetObjectKind().GroupVersionKind() != r.obj.GroupVersionKind() || key != client.ObjectKeyFromObject(r.obj) {
+		return fmt.Errorf("unexpected get of %s %s", obj.GetObjectKind().GroupVersionKind().String(), key.String())
+	}
+
+	obj.(*unstructured.Unstructured).Object = r.obj.DeepCopy().Object
+	return nil
+}
+
+func (r *objectReader) List(_ context.Context, _ client.ObjectList, _ ...client.ListOption) error {
+	return fmt.Errorf("unexpected list")
+}
@@ -0,0 +1,354 @@
+package syncers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/vcluster/pkg/mappings/generic"
+	"github.com/loft-sh/vcluster/pkg/patcher"
+	"github.com/loft-sh/vcluster/pkg/syncer"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	"github.com/loft-sh/vcluster/pkg/syncer/translator"
+	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
+	"github.com/loft-sh/vcluster/pkg/util"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CRDOptions configure how a custom resource is synced to the host cluster
+type CRDOptions struct {
+	// CRD is the manifest of the custom resource definition that is applied in the host and the
+	// virtual cluster. If empty, the definition has to exist in the host cluster already and is
+	// copied into the virtual cluster.
+	CRD []byte
+
+	// Name is the name of the syncer, defaults to the lower case kind
+	Name string
+
+	// HostName translates the virtual name of an object to its host name, defaults to
+	// translate.Default.HostName
+	HostName generic.PhysicalNameFunc
+
+	// Fields define which fields are synced in which direction. Defaults to DefaultCRDFields.
+	Fields []FieldSpec
+
+	// References are fields of the custom resource that reference other objects by name,
+	// which are translated to the host names
+	References []Reference
+
+	// ConflictPolicy decides which side wins if a field synced in both directions changed
+	// in the virtual and the host object, defaults to ConflictVirtualWins
+	ConflictPolicy ConflictPolicy
+
+	// Adoption defines what happens to host objects without a virtual object that were
+	// left by a previous vCluster, defaults to AdoptionDelete
+	Adoption AdoptionPolicy
+
+	// Conversion lets the virtual cluster serve a different version than the host cluster.
+	// The kind passed to the syncer is the virtual version and CRD is only applied in the
+	// virtual cluster, the host cluster needs to serve Conversion.HostVersion already.
+	Conversion *Conversion
+}
+
+// DefaultCRDFields sync the spec and metadata down to the host and the status back up
+var DefaultCRDFields = []FieldSpec{
+	{Path: "metadata.labels", Direction: FieldToHost},
+	{Path: "metadata.annotations", Direction: FieldToHost},
+	{Path: "spec", Direction: FieldToHost},
+	{Path: "status", Direction: FieldToVirtual},
+}
+
+// NewCRDSyncer creates a syncer that syncs the custom resources of the given kind to the host
+// cluster. It makes sure the custom resource definition exists in both clusters and registers
+// a mapper for the kind, so other syncers can translate references to the resources. Fields
+// without a field spec are only copied when the host object is created.
+func NewCRDSyncer(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind, options CRDOptions) (syncertypes.Syncer, error) {
+	fieldSpecs := options.Fields
+	if fieldSpecs == nil {
+		fieldSpecs = DefaultCRDFields
+	}
+	fields, err := NewFieldSync(fieldSpecs, options.ConflictPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	var conversion *converter
+	if options.Conversion != nil {
+		if len(options.CRD) == 0 {
+			return nil, fmt.Errorf("the crd manifest of version %s is required for a conversion", gvk.Version)
+		}
+
+		conversion, err = newConverter(gvk, *options.Conversion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// check the scope before the crd is applied, so a cluster scoped crd is never
+	// installed in the shared host cluster
+	isClusterScoped, err := crdIsClusterScoped(ctx, gvk, options.CRD)
+	if err != nil {
+		return nil, fmt.Errorf("ensure crd %s: %w", gvk.String(), err)
+	} else if isClusterScoped {
+		return nil, fmt.Errorf("custom resource %s is cluster scoped, only namespaced custom resources can be synced to the host", gvk.String())
+	}
+
+	_, hasStatusSubresource, err := ensureCRD(ctx, gvk, options.CRD, conversion)
+	if err != nil {
+		return nil, fmt.Errorf("ensure crd %s: %w", gvk.String(), err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+
+	var mapper synccontext.Mapper
+	if ctx.Mappings.Has(gvk) {
+		// reuse the mapper registered through Options.RegisterMappings
+		mapper, err = ctx.Mappings.ByGVK(gvk)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		hostName := options.HostName
+		if hostName == nil {
+			hostName = translate.Default.HostName
+		}
+
+		mapper, err = generic.NewMapper(ctx, obj, hostName)
+		if err != nil {
+			return nil, fmt.Errorf("create mapper for %s: %w", gvk.String(), err)
+		}
+
+		err = ctx.Mappings.AddMapper(mapper)
+		if err != nil {
+			return nil, fmt.Errorf("add mapper %s: %w", gvk.String(), err)
+		}
+	}
+
+	references, err := NewReferences(ctx, options.References...)
+	if err != nil {
+		return nil, err
+	}
+	fields.WithReferences(references)
+
+	name := options.Name
+	if name == "" {
+		name = strings.ToLower(gvk.Kind)
+	}
+
+	genericTranslator := translator.NewGenericTranslator(ctx, name, obj, mapper)
+	adopter, err := NewAdopter(gvk, options.Adoption, genericTranslator.EventRecorder())
+	if err != nil {
+		return nil, err
+	}
+
+	return &crdSyncer{
+		GenericTranslator: genericTranslator,
+		Adopter:           adopter,
+
+		fields:               fields,
+		references:           references,
+		converter:            conversion,
+		hasStatusSubresource: hasStatusSubresource,
+	}, nil
+}
+
+type crdSyncer struct {
+	syncertypes.GenericTranslator
+	*Adopter
+
+	fields               *FieldSync
+	references           *References
+	converter            *converter
+	hasStatusSubresource bool
+}
+
+var _ syncertypes.Syncer = &crdSyncer{}
+
+var _ syncertypes.ManagerProvider = &crdSyncer{}
+
+var _ syncertypes.OptionsProvider = &crdSyncer{}
+
+var _ syncertypes.Importer = &crdSyncer{}
+
+func (s *crdSyncer) Options() *syncertypes.Options {
+	// the old objects are needed for fields synced in both directions
+	return &syncertypes.Options{
+		ObjectCaching:      true,
+		DisableUIDDeletion: s.DisableUIDDeletion(),
+	}
+}
+
+// ConfigureAndStartManager exchanges the host manager with one that converts the custom
+// resources to the virtual version, if a conversion is configured
+func (s *crdSyncer) ConfigureAndStartManager(ctx *synccontext.RegisterContext) (*synccontext.RegisterContext, error) {
+	if s.converter == nil {
+		return ctx, nil
+	}
+
+	return s.converter.withConversion(ctx), nil
+}
+
+// Migrate lists the host objects through the converting host manager as well
+func (s *crdSyncer) Migrate(ctx *synccontext.RegisterContext, mapper synccontext.Mapper) error {
+	ctx, err := s.ConfigureAndStartManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.GenericTranslator.Migrate(ctx, mapper)
+}
+
+func (s *crdSyncer) Syncer() syncertypes.Sync[client.Object] {
+	return syncer.ToGenericSyncer[*unstructured.Unstructured](s)
+}
+
+func (s *crdSyncer) SyncToHost(ctx *synccontext.SyncContext, event *synccontext.SyncToHostEvent[*unstructured.Unstructured]) (ctrl.Result, error) {
+	pObj := translate.HostMetadata(event.Virtual, s.VirtualToHost(ctx, types.NamespacedName{Name: event.Virtual.GetName(), Namespace: event.Virtual.GetNamespace()}, event.Virtual))
+
+	// fields that are owned by the host are not copied down
+	err := s.fields.RemoveHostOwned(pObj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if s.references != nil {
+		owner, err := synccontext.NewNameMappingFrom(pObj, event.Virtual)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		err = s.references.ToHost(ctx, owner, pObj)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("translate references: %w", err)
+		}
+	}
+
+	return patcher.CreateHostObject(ctx, event.Virtual, pObj, s.EventRecorder(), s.hasStatusSubresource)
+}
+
+func (s *crdSyncer) Sync(ctx *synccontext.SyncContext, event *synccontext.SyncEvent[*unstructured.Unstructured]) (ctrl.Result, error) {
+	err := s.Takeover(ctx, event.Virtual, event.Host)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var options []patcher.Option
+	if !s.hasStatusSubresource {
+		options = append(options, patcher.NoStatusSubResource())
+	}
+
+	return SyncFields(ctx, event, s.fields, s.EventRecorder(), options...)
+}
+
+func (s *crdSyncer) SyncToVirtual(ctx *synccontext.SyncContext, event *synccontext.SyncToVirtualEvent[*unstructured.Unstructured]) (ctrl.Result, error) {
+	result, handled, err := s.HandleOrphan(ctx, event.Host, event.VirtualOld, s.hasStatusSubresource)
+	if handled {
+		return result, err
+	}
+
+	// virtual object is not here anymore, so we delete
+	return patcher.DeleteHostObject(ctx, event.Host, event.VirtualOld, "virtual object was deleted")
+}
+
+// ensureCRD makes sure the custom resource definition exists in the host and the virtual
+// cluster and returns if the resource is cluster scoped and has a status subresource. With
+// a conversion the manifest is only applied in the virtual cluster. Check the scope with
+// crdIsClusterScoped before, as the crd is applied in both clusters right away.
+func ensureCRD(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind, manifest []byte, conversion *converter) (bool, bool, error) {
+	if len(manifest) == 0 {
+		return translate.EnsureCRDFromPhysicalCluster(ctx.Context, ctx.HostManager.GetConfig(), ctx.VirtualManager.GetConfig(), gvk)
+	}
+
+	crd, version, err := parseCRD(gvk, manifest)
+	if err != nil {
+		return false, false, err
+	}
+
+	isClusterScoped := crd.Spec.Scope == apiextensionsv1.ClusterScoped
+	subresources := crd.Spec.Versions[version].Subresources
+	hasStatus := subresources != nil && subresources.Status != nil
+	if conversion != nil {
+		err = ensureHostVersion(ctx, conversion.hostGVK, isClusterScoped, hasStatus)
+		if err != nil {
+			return false, false, err
+		}
+	} else {
+		err = util.EnsureCRD(ctx.Context, ctx.HostManager.GetConfig(), manifest, gvk)
+		if err != nil {
+			return false, false, fmt.Errorf("host cluster: %w", err)
+		}
+	}
+
+	err = util.EnsureCRD(ctx.Context, ctx.VirtualManager.GetConfig(), manifest, gvk)
+	if err != nil {
+		return false, false, fmt.Errorf("virtual cluster: %w", err)
+	}
+
+	return isClusterScoped, hasStatus, nil
+}
+
+// crdIsClusterScoped returns if the kind is cluster scoped according to the crd manifest or,
+// without a manifest, the host cluster
+func crdIsClusterScoped(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind, manifest []byte) (bool, error) {
+	if len(manifest) == 0 {
+		mapping, err := ctx.HostManager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return false, fmt.Errorf("host cluster: %w", err)
+		}
+
+		return mapping.Scope.Name() == meta.RESTScopeNameRoot, nil
+	}
+
+	crd, _, err := parseCRD(gvk, manifest)
+	if err != nil {
+		return false, err
+	}
+
+	return crd.Spec.Scope == apiextensionsv1.ClusterScoped, nil
+}
+
+// parseCRD parses the crd manifest and returns the index of the version of the kind
+func parseCRD(gvk schema.GroupVersionKind, manifest []byte) (*apiextensionsv1.CustomResourceDefinition, int, error) {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	err := yaml.Unmarshal(manifest, crd)
+	if err != nil {
+		return nil, 0, fmt.Errorf("parse crd manifest: %w", err)
+	}
+
+	version := slices.IndexFunc(crd.Spec.Versions, func(version apiextensionsv1.CustomResourceDefinitionVersion) bool {
+		return version.Name == gvk.Version
+	})
+	if crd.Spec.Group != gvk.Group || crd.Spec.Names.Kind != gvk.Kind || version == -1 {
+		return nil, 0, fmt.Errorf("crd manifest %s does not define %s", crd.Name, gvk.String())
+	}
+
+	return crd, version, nil
+}
+
+// ensureHostVersion checks that the host cluster serves the host version of a conversion
+// with the same scope and status subresource as the virtual version
+func ensureHostVersion(ctx *synccontext.RegisterContext, hostGVK schema.GroupVersionKind, isClusterScoped, hasStatus bool) error {
+	mapping, err := ctx.HostManager.GetRESTMapper().RESTMapping(hostGVK.GroupKind(), hostGVK.Version)
+	if err != nil {
+		return fmt.Errorf("host cluster does not serve %s: %w", hostGVK.String(), err)
+	} else if (mapping.Scope.Name() == meta.RESTScopeNameRoot) != isClusterScoped {
+		return fmt.Errorf("scope of %s in the host cluster differs from the crd manifest", hostGVK.String())
+	}
+
+	hostHasStatus, err := hasStatusSubresourceIn(ctx.HostManager.GetConfig(), mapping.Resource)
+	if err != nil {
+		return err
+	} else if hostHasStatus != hasStatus {
+		return fmt.Errorf("status subresource of %s in the host cluster differs from the crd manifest", hostGVK.String())
+	}
+
+	return nil
+}
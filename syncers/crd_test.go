@@ -0,0 +1,79 @@
+package syncers
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCRDIsClusterScoped(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Example"}
+	newManifest := func(group, version, scope string) string {
+		return `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: examples.` + group + `
+spec:
+  group: ` + group + `
+  names:
+    kind: Example
+    plural: examples
+  scope: ` + scope + `
+  versions:
+  - name: ` + version + `
+    served: true
+    storage: true
+`
+	}
+
+	testCases := []struct {
+		name     string
+		manifest string
+		expected bool
+		err      string
+	}{
+		{
+			name:     "namespaced",
+			manifest: newManifest("example.com", "v1", "Namespaced"),
+		},
+		{
+			name:     "cluster scoped",
+			manifest: newManifest("example.com", "v1", "Cluster"),
+			expected: true,
+		},
+		{
+			name:     "other group",
+			manifest: newManifest("other.com", "v1", "Namespaced"),
+			err:      "does not define",
+		},
+		{
+			name:     "missing version",
+			manifest: newManifest("example.com", "v2", "Namespaced"),
+			err:      "does not define",
+		},
+		{
+			name:     "invalid manifest",
+			manifest: "spec: [",
+			err:      "parse crd manifest",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			isClusterScoped, err := crdIsClusterScoped(nil, gvk, []byte(testCase.manifest))
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if isClusterScoped != testCase.expected {
+				t.Fatalf("expected cluster scoped %v, got %v", testCase.expected, isClusterScoped)
+			}
+		})
+	}
+}
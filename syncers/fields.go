@@ -0,0 +1,458 @@
+package syncers
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/loft-sh/vcluster/pkg/patcher"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	"github.com/loft-sh/vcluster/pkg/util/clienthelper"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FieldDirection defines in which direction a field is synced
+type FieldDirection string
+
+const (
+	// FieldToHost syncs the field from the virtual object to the host object
+	FieldToHost FieldDirection = "host"
+
+	// FieldToVirtual syncs the field from the host object to the virtual object
+	FieldToVirtual FieldDirection = "virtual"
+
+	// FieldBoth syncs changes of the field in either object to the other one
+	FieldBoth FieldDirection = "both"
+)
+
+// ConflictPolicy decides which side wins if a field synced in both directions changed in
+// the virtual and the host object
+type ConflictPolicy string
+
+const (
+	// ConflictVirtualWins overwrites the host field with the virtual one
+	ConflictVirtualWins ConflictPolicy = "VirtualWins"
+
+	// ConflictHostWins overwrites the virtual field with the host one
+	ConflictHostWins ConflictPolicy = "HostWins"
+)
+
+var fieldSpecRegEx = regexp.MustCompile(`^\s*(\S+)\s*(<->|->)\s*(host|virtual|both)?\s*$`)
+
+// FieldSpec defines the sync direction of a field
+type FieldSpec struct {
+	// Path is the JSONPath of the field, e.g. spec.replicas or .status.conditions. A * segment
+	// matches all keys of a map, a trailing * the whole field. Of the metadata only
+	// metadata.labels and metadata.annotations can be synced, which are translated the same
+	// way vCluster translates them.
+	Path string
+
+	// Direction is the direction the field is synced in
+	Direction FieldDirection
+}
+
+// ParseFieldSpec parses a field spec in the form of "spec.* -> host", "status.* -> virtual"
+// or "metadata.labels <-> both"
+func ParseFieldSpec(spec string) (FieldSpec, error) {
+	matches := fieldSpecRegEx.FindStringSubmatch(spec)
+	if matches == nil {
+		return FieldSpec{}, fmt.Errorf("field spec %q is invalid, expected PATH -> host, PATH -> virtual or PATH <-> both", spec)
+	}
+
+	direction := FieldDirection(matches[3])
+	if matches[2] == "<->" {
+		if direction != "" && direction != FieldBoth {
+			return FieldSpec{}, fmt.Errorf("field spec %q is invalid, <-> can only be used with both", spec)
+		}
+
+		direction = FieldBoth
+	} else if direction != FieldToHost && direction != FieldToVirtual {
+		return FieldSpec{}, fmt.Errorf("field spec %q is invalid, -> can only be used with host or virtual", spec)
+	}
+
+	return FieldSpec{Path: matches[1], Direction: direction}, nil
+}
+
+// ParseFieldSpecs parses the given field specs, see ParseFieldSpec
+func ParseFieldSpecs(specs ...string) ([]FieldSpec, error) {
+	fieldSpecs := make([]FieldSpec, 0, len(specs))
+	for _, spec := range specs {
+		fieldSpec, err := ParseFieldSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		fieldSpecs = append(fieldSpecs, fieldSpec)
+	}
+
+	return fieldSpecs, nil
+}
+
+// FieldSync syncs the fields of a virtual and a host object according to field specs.
+// Fields synced in both directions are compared with the old objects of the sync event to
+// find out which side changed, so syncers using it should enable ObjectCaching. Without
+// old objects the conflict policy decides.
+type FieldSync struct {
+	fields         []fieldPath
+	conflictPolicy ConflictPolicy
+	references     *References
+}
+
+type fieldPath struct {
+	path      []string
+	direction FieldDirection
+}
+
+// NewFieldSync creates a new field sync for the given specs. Specs that overlap need to
+// have the same direction. The conflict policy defaults to ConflictVirtualWins.
+func NewFieldSync(specs []FieldSpec, conflictPolicy ConflictPolicy) (*FieldSync, error) {
+	if conflictPolicy == "" {
+		conflictPolicy = ConflictVirtualWins
+	} else if conflictPolicy != ConflictVirtualWins && conflictPolicy != ConflictHostWins {
+		return nil, fmt.Errorf("unsupported conflict policy %s", conflictPolicy)
+	}
+
+	fields := make([]fieldPath, 0, len(specs))
+	for _, spec := range specs {
+		if spec.Direction != FieldToHost && spec.Direction != FieldToVirtual && spec.Direction != FieldBoth {
+			return nil, fmt.Errorf("field %s has unsupported direction %q", spec.Path, spec.Direction)
+		}
+
+		path, err := parseFieldPath(spec.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range fields {
+			if field.direction != spec.Direction && pathsOverlap(field.path, path) {
+				return nil, fmt.Errorf("field %s is synced to %s, but overlaps with %s, which is synced to %s", spec.Path, spec.Direction, strings.Join(field.path, "."), field.direction)
+			}
+		}
+
+		fields = append(fields, fieldPath{path: path, direction: spec.Direction})
+	}
+
+	return &FieldSync{
+		fields:         fields,
+		conflictPolicy: conflictPolicy,
+	}, nil
+}
+
+// WithReferences makes SyncFields translate the given references, so fields are compared
+// and copied with the host names of the referenced objects
+func (f *FieldSync) WithReferences(references *References) *FieldSync {
+	f.references = references
+	return f
+}
+
+// SyncFields is a Sync implementation for syncers that sync the fields of the event objects
+// according to the field sync. Conflicts are reported as events on the virtual object.
+func SyncFields[T client.Object](ctx *synccontext.SyncContext, event *synccontext.SyncEvent[T], fields *FieldSync, eventRecorder events.EventRecorder, options ...patcher.Option) (_ ctrl.Result, retErr error) {
+	patchHelper, err := patcher.NewSyncerPatcher(ctx, event.Host, event.Virtual, options...)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("new syncer patcher: %w", err)
+	}
+
+	defer func() {
+		if err := patchHelper.Patch(ctx, event.Host, event.Virtual); err != nil {
+			retErr = utilerrors.NewAggregate([]error{retErr, err})
+		}
+		if retErr != nil {
+			eventRecorder.Eventf(event.Virtual, nil, "Warning", "SyncError", "SyncError", "Error syncing: %v", retErr)
+		}
+	}()
+
+	// any changes made below here are correctly synced
+
+	var virtual, virtualOld, hostOld client.Object = event.Virtual, nil, nil
+	if !clienthelper.IsNilObject(event.VirtualOld) {
+		virtualOld = event.VirtualOld
+	}
+	if !clienthelper.IsNilObject(event.HostOld) {
+		hostOld = event.HostOld
+	}
+
+	// compare and copy the fields with the host names of referenced objects
+	var owner synccontext.NameMapping
+	if fields.references != nil {
+		owner, err = synccontext.NewNameMappingFrom(event.Host, event.Virtual)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		virtual = event.Virtual.DeepCopyObject().(client.Object)
+		err = fields.references.ToHost(ctx, owner, virtual)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("translate references: %w", err)
+		}
+		if virtualOld != nil {
+			virtualOld = virtualOld.DeepCopyObject().(client.Object)
+			err = fields.references.toHost(ctx, owner, virtualOld, false)
+			if err != nil {
+				return ctrl.Result{}, fmt.Errorf("translate references: %w", err)
+			}
+		}
+	}
+
+	conflicts, err := fields.Apply(virtualOld, virtual, hostOld, event.Host)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if fields.references != nil {
+		err = fields.references.ToVirtual(ctx, owner, virtual)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("translate references: %w", err)
+		}
+
+		virtualMap, err := toUnstructured(virtual)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		err = fromUnstructured(virtualMap, event.Virtual)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if len(conflicts) > 0 {
+		eventRecorder.Eventf(event.Virtual, nil, "Warning", "SyncConflict", "SyncConflict", "Fields %s changed in the virtual and host object, %s", strings.Join(conflicts, ", "), fields.conflictPolicy)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// Apply syncs the fields of the virtual and host object. The old objects can be nil. It
+// returns the fields that changed in both objects.
+func (f *FieldSync) Apply(virtualOld, virtual, hostOld, host client.Object) ([]string, error) {
+	conflicts := []string{}
+
+	// labels and annotations need to be translated
+	for _, field := range f.fields {
+		if field.path[0] != "metadata" {
+			continue
+		}
+
+		get := client.Object.GetLabels
+		if field.path[1] == "annotations" {
+			get = client.Object.GetAnnotations
+		}
+
+		direction, conflict := f.resolve(field.direction,
+			virtualOld != nil && !equality.Semantic.DeepEqual(get(virtualOld), get(virtual)),
+			hostOld != nil && !equality.Semantic.DeepEqual(get(hostOld), get(host)),
+		)
+		if conflict {
+			conflicts = append(conflicts, strings.Join(field.path, "."))
+		}
+
+		switch {
+		case direction == FieldToHost && field.path[1] == "labels":
+			host.SetLabels(translate.HostLabels(virtual, host))
+		case direction == FieldToHost:
+			host.SetAnnotations(translate.HostAnnotations(virtual, host))
+		case field.path[1] == "labels":
+			virtual.SetLabels(translate.VirtualLabels(host, virtual))
+		default:
+			virtual.SetAnnotations(translate.VirtualAnnotations(host, virtual))
+		}
+	}
+
+	virtualMap, err := toUnstructured(virtual)
+	if err != nil {
+		return nil, err
+	}
+	hostMap, err := toUnstructured(host)
+	if err != nil {
+		return nil, err
+	}
+	var virtualOldMap, hostOldMap map[string]interface{}
+	if virtualOld != nil {
+		virtualOldMap, err = toUnstructured(virtualOld)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hostOld != nil {
+		hostOldMap, err = toUnstructured(hostOld)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, field := range f.fields {
+		if field.path[0] == "metadata" {
+			continue
+		}
+
+		for _, path := range expandFieldPath(field.path, virtualOldMap, virtualMap, hostOldMap, hostMap) {
+			virtualValue, virtualFound, _ := unstructured.NestedFieldNoCopy(virtualMap, path...)
+			hostValue, hostFound, _ := unstructured.NestedFieldNoCopy(hostMap, path...)
+			if virtualFound == hostFound && equality.Semantic.DeepEqual(virtualValue, hostValue) {
+				continue
+			}
+
+			direction, conflict := f.resolve(field.direction,
+				virtualOldMap != nil && fieldChanged(virtualOldMap, virtualMap, path),
+				hostOldMap != nil && fieldChanged(hostOldMap, hostMap, path),
+			)
+			if conflict {
+				conflicts = append(conflicts, strings.Join(path, "."))
+			}
+
+			if direction == FieldToHost {
+				err = copyField(&unstructured.Unstructured{Object: virtualMap}, &unstructured.Unstructured{Object: hostMap}, path)
+			} else {
+				err = copyField(&unstructured.Unstructured{Object: hostMap}, &unstructured.Unstructured{Object: virtualMap}, path)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("sync %s to %s: %w", strings.Join(path, "."), direction, err)
+			}
+		}
+	}
+
+	err = fromUnstructured(virtualMap, virtual)
+	if err != nil {
+		return nil, err
+	}
+	err = fromUnstructured(hostMap, host)
+	if err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+// RemoveHostOwned removes the fields that are only synced to the virtual object from a new
+// host object, so they are not copied down on creation
+func (f *FieldSync) RemoveHostOwned(host client.Object) error {
+	hostMap, err := toUnstructured(host)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range f.fields {
+		if field.direction != FieldToVirtual || field.path[0] == "metadata" {
+			continue
+		}
+
+		for _, path := range expandFieldPath(field.path, hostMap) {
+			unstructured.RemoveNestedField(hostMap, path...)
+		}
+	}
+
+	return fromUnstructured(hostMap, host)
+}
+
+// resolve returns the direction a field is synced in, depending on which side changed. It
+// returns true if both sides changed.
+func (f *FieldSync) resolve(direction FieldDirection, virtualChanged, hostChanged bool) (FieldDirection, bool) {
+	if direction != FieldBoth {
+		return direction, false
+	}
+
+	switch {
+	case virtualChanged && !hostChanged:
+		return FieldToHost, false
+	case hostChanged && !virtualChanged:
+		return FieldToVirtual, false
+	case f.conflictPolicy == ConflictHostWins:
+		return FieldToVirtual, virtualChanged && hostChanged
+	default:
+		return FieldToHost, virtualChanged && hostChanged
+	}
+}
+
+// parseFieldPath splits a JSONPath into its segments
+func parseFieldPath(path string) ([]string, error) {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	trimmed = strings.TrimPrefix(strings.TrimPrefix(trimmed, "$"), ".")
+	segments := strings.Split(trimmed, ".")
+	if segments[len(segments)-1] == "*" {
+		segments = segments[:len(segments)-1]
+	}
+	if len(segments) == 0 || slices.Contains(segments, "") {
+		return nil, fmt.Errorf("field path %q is invalid", path)
+	}
+
+	switch segments[0] {
+	case "apiVersion", "kind":
+		return nil, fmt.Errorf("field path %q is invalid, %s cannot be synced", path, segments[0])
+	case "metadata":
+		if len(segments) != 2 || (segments[1] != "labels" && segments[1] != "annotations") {
+			return nil, fmt.Errorf("field path %q is invalid, only metadata.labels and metadata.annotations can be synced", path)
+		}
+	}
+
+	return segments, nil
+}
+
+// expandFieldPath resolves the * segments of the path with the map keys of the objects
+func expandFieldPath(path []string, objs ...map[string]interface{}) [][]string {
+	wildcard := slices.Index(path, "*")
+	if wildcard == -1 {
+		return [][]string{path}
+	}
+
+	keys := []string{}
+	for _, obj := range objs {
+		value, found, _ := unstructured.NestedFieldNoCopy(obj, path[:wildcard]...)
+		valueMap, ok := value.(map[string]interface{})
+		if !found || !ok {
+			continue
+		}
+
+		for key := range valueMap {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.Sort(keys)
+
+	paths := [][]string{}
+	for _, key := range keys {
+		expanded := slices.Concat(path[:wildcard], []string{key}, path[wildcard+1:])
+		paths = append(paths, expandFieldPath(expanded, objs...)...)
+	}
+
+	return paths
+}
+
+// pathsOverlap checks if one path is a prefix of the other, * segments match any key
+func pathsOverlap(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] && a[i] != "*" && b[i] != "*" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func fieldChanged(oldObj, newObj map[string]interface{}, path []string) bool {
+	oldValue, oldFound, _ := unstructured.NestedFieldNoCopy(oldObj, path...)
+	newValue, newFound, _ := unstructured.NestedFieldNoCopy(newObj, path...)
+	return oldFound != newFound || !equality.Semantic.DeepEqual(oldValue, newValue)
+}
+
+// copyField copies the field at the path from one object to the other and removes it from
+// the target if it isn't set
+func copyField(from, to *unstructured.Unstructured, path []string) error {
+	value, found, err := unstructured.NestedFieldNoCopy(from.Object, path...)
+	if err != nil {
+		return err
+	} else if !found {
+		unstructured.RemoveNestedField(to.Object, path...)
+		return nil
+	}
+
+	return unstructured.SetNestedField(to.Object, runtime.DeepCopyJSONValue(value), path...)
+}
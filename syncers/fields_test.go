@@ -0,0 +1,317 @@
+package syncers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestParseFieldSpec(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		expected FieldSpec
+		err      string
+	}{
+		{spec: "spec.* -> host", expected: FieldSpec{Path: "spec.*", Direction: FieldToHost}},
+		{spec: "status.* -> virtual", expected: FieldSpec{Path: "status.*", Direction: FieldToVirtual}},
+		{spec: "metadata.labels <-> both", expected: FieldSpec{Path: "metadata.labels", Direction: FieldBoth}},
+		{spec: "metadata.annotations <->", expected: FieldSpec{Path: "metadata.annotations", Direction: FieldBoth}},
+		{spec: "  .spec.replicas->host  ", expected: FieldSpec{Path: ".spec.replicas", Direction: FieldToHost}},
+		{spec: "spec.replicas -> both", err: "-> can only be used with host or virtual"},
+		{spec: "spec.replicas -> ", err: "-> can only be used with host or virtual"},
+		{spec: "spec.replicas <-> host", err: "<-> can only be used with both"},
+		{spec: "spec.replicas => host", err: "is invalid"},
+		{spec: "-> host", err: "is invalid"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.spec, func(t *testing.T) {
+			fieldSpec, err := ParseFieldSpec(testCase.spec)
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if fieldSpec != testCase.expected {
+				t.Fatalf("expected %#v, got %#v", testCase.expected, fieldSpec)
+			}
+		})
+	}
+}
+
+func TestCopyField(t *testing.T) {
+	testCases := []struct {
+		name     string
+		from     map[string]interface{}
+		to       map[string]interface{}
+		path     []string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "set missing field",
+			from:     map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2)}},
+			to:       map[string]interface{}{},
+			path:     []string{"spec", "replicas"},
+			expected: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2)}},
+		},
+		{
+			name:     "overwrite field",
+			from:     map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2)}},
+			to:       map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1), "paused": true}},
+			path:     []string{"spec", "replicas"},
+			expected: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2), "paused": true}},
+		},
+		{
+			name:     "remove unset field",
+			from:     map[string]interface{}{"spec": map[string]interface{}{}},
+			to:       map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1), "paused": true}},
+			path:     []string{"spec", "replicas"},
+			expected: map[string]interface{}{"spec": map[string]interface{}{"paused": true}},
+		},
+		{
+			name:     "copy nested map",
+			from:     map[string]interface{}{"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Ready"}}}},
+			to:       map[string]interface{}{},
+			path:     []string{"status"},
+			expected: map[string]interface{}{"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Ready"}}}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			from := &unstructured.Unstructured{Object: testCase.from}
+			to := &unstructured.Unstructured{Object: testCase.to}
+			err := copyField(from, to, testCase.path)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			} else if !reflect.DeepEqual(to.Object, testCase.expected) {
+				t.Fatalf("expected %v, got %v", testCase.expected, to.Object)
+			}
+
+			// the copied value must not be shared with the source
+			unstructured.RemoveNestedField(from.Object, testCase.path...)
+			if !reflect.DeepEqual(to.Object, testCase.expected) {
+				t.Fatalf("copied value changed with the source: %v", to.Object)
+			}
+		})
+	}
+}
+
+func TestMergeChangesInto(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old      map[string]interface{}
+		new      map[string]interface{}
+		out      map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "no changes",
+			old:      map[string]interface{}{"a": "1"},
+			new:      map[string]interface{}{"a": "1"},
+			out:      map[string]interface{}{"a": "2", "b": "3"},
+			expected: map[string]interface{}{"a": "2", "b": "3"},
+		},
+		{
+			name:     "changed field",
+			old:      map[string]interface{}{"a": "1", "b": "1"},
+			new:      map[string]interface{}{"a": "2", "b": "1"},
+			out:      map[string]interface{}{"a": "1", "b": "3"},
+			expected: map[string]interface{}{"a": "2", "b": "3"},
+		},
+		{
+			name:     "removed field",
+			old:      map[string]interface{}{"a": "1", "b": "1"},
+			new:      map[string]interface{}{"b": "1"},
+			out:      map[string]interface{}{"a": "1", "b": "3"},
+			expected: map[string]interface{}{"b": "3"},
+		},
+		{
+			name:     "integers stay int64",
+			old:      map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			new:      map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2)}},
+			out:      map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1), "port": int64(80)}},
+			expected: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2), "port": int64(80)}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			merged, err := mergeChangesInto(testCase.old, testCase.new, testCase.out)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			} else if !reflect.DeepEqual(merged, testCase.expected) {
+				t.Fatalf("expected %v, got %v", testCase.expected, merged)
+			}
+		})
+	}
+}
+
+func TestFieldSyncApply(t *testing.T) {
+	newObject := func(replicas, ready int64) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Example",
+			"metadata":   map[string]interface{}{"name": "test"},
+			"spec":       map[string]interface{}{"replicas": replicas},
+			"status":     map[string]interface{}{"ready": ready},
+		}}
+	}
+
+	testCases := []struct {
+		name           string
+		specs          []string
+		conflictPolicy ConflictPolicy
+
+		virtualOld, virtual, hostOld, host *unstructured.Unstructured
+
+		expectedVirtual, expectedHost *unstructured.Unstructured
+		expectedConflicts             []string
+	}{
+		{
+			name:            "one direction per field",
+			specs:           []string{"spec.* -> host", "status.* -> virtual"},
+			virtual:         newObject(2, 0),
+			host:            newObject(1, 1),
+			expectedVirtual: newObject(2, 1),
+			expectedHost:    newObject(2, 1),
+		},
+		{
+			name:            "both directions with virtual change",
+			specs:           []string{"spec.replicas <-> both"},
+			virtualOld:      newObject(1, 0),
+			virtual:         newObject(2, 0),
+			hostOld:         newObject(1, 0),
+			host:            newObject(1, 0),
+			expectedVirtual: newObject(2, 0),
+			expectedHost:    newObject(2, 0),
+		},
+		{
+			name:            "both directions with host change",
+			specs:           []string{"spec.replicas <-> both"},
+			virtualOld:      newObject(1, 0),
+			virtual:         newObject(1, 0),
+			hostOld:         newObject(1, 0),
+			host:            newObject(3, 0),
+			expectedVirtual: newObject(3, 0),
+			expectedHost:    newObject(3, 0),
+		},
+		{
+			name:              "conflict with virtual wins",
+			specs:             []string{"spec.replicas <-> both"},
+			virtualOld:        newObject(1, 0),
+			virtual:           newObject(2, 0),
+			hostOld:           newObject(1, 0),
+			host:              newObject(3, 0),
+			expectedVirtual:   newObject(2, 0),
+			expectedHost:      newObject(2, 0),
+			expectedConflicts: []string{"spec.replicas"},
+		},
+		{
+			name:              "conflict with host wins",
+			specs:             []string{"spec.replicas <-> both"},
+			conflictPolicy:    ConflictHostWins,
+			virtualOld:        newObject(1, 0),
+			virtual:           newObject(2, 0),
+			hostOld:           newObject(1, 0),
+			host:              newObject(3, 0),
+			expectedVirtual:   newObject(3, 0),
+			expectedHost:      newObject(3, 0),
+			expectedConflicts: []string{"spec.replicas"},
+		},
+		{
+			name:            "unspecified fields are not synced",
+			specs:           []string{"status.ready -> virtual"},
+			virtual:         newObject(2, 0),
+			host:            newObject(1, 1),
+			expectedVirtual: newObject(2, 1),
+			expectedHost:    newObject(1, 1),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			specs, err := ParseFieldSpecs(testCase.specs...)
+			if err != nil {
+				t.Fatalf("parse specs: %v", err)
+			}
+			fields, err := NewFieldSync(specs, testCase.conflictPolicy)
+			if err != nil {
+				t.Fatalf("new field sync: %v", err)
+			}
+
+			conflicts, err := fields.Apply(objectOrNil(testCase.virtualOld), testCase.virtual, objectOrNil(testCase.hostOld), testCase.host)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if !reflect.DeepEqual(testCase.virtual.Object, testCase.expectedVirtual.Object) {
+				t.Fatalf("expected virtual %v, got %v", testCase.expectedVirtual.Object, testCase.virtual.Object)
+			} else if !reflect.DeepEqual(testCase.host.Object, testCase.expectedHost.Object) {
+				t.Fatalf("expected host %v, got %v", testCase.expectedHost.Object, testCase.host.Object)
+			} else if len(conflicts) != len(testCase.expectedConflicts) || (len(conflicts) > 0 && !reflect.DeepEqual(conflicts, testCase.expectedConflicts)) {
+				t.Fatalf("expected conflicts %v, got %v", testCase.expectedConflicts, conflicts)
+			}
+		})
+	}
+}
+
+func TestNewFieldSync(t *testing.T) {
+	testCases := []struct {
+		name  string
+		specs []FieldSpec
+		err   string
+	}{
+		{
+			name:  "overlapping fields with different directions",
+			specs: []FieldSpec{{Path: "spec.*", Direction: FieldToHost}, {Path: "spec.replicas", Direction: FieldToVirtual}},
+			err:   "overlap",
+		},
+		{
+			name:  "overlapping fields with the same direction",
+			specs: []FieldSpec{{Path: "spec.*", Direction: FieldToHost}, {Path: "spec.replicas", Direction: FieldToHost}},
+		},
+		{
+			name:  "unsupported metadata field",
+			specs: []FieldSpec{{Path: "metadata.name", Direction: FieldToHost}},
+			err:   "only metadata.labels and metadata.annotations",
+		},
+		{
+			name:  "kind",
+			specs: []FieldSpec{{Path: "kind", Direction: FieldToHost}},
+			err:   "cannot be synced",
+		},
+		{
+			name:  "unsupported direction",
+			specs: []FieldSpec{{Path: "spec", Direction: "up"}},
+			err:   "unsupported direction",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := NewFieldSync(testCase.specs, "")
+			if testCase.err == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			} else if testCase.err != "" && (err == nil || !strings.Contains(err.Error(), testCase.err)) {
+				t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+			}
+		})
+	}
+}
+
+// objectOrNil returns an untyped nil for unset old objects, as Apply checks for nil
+func objectOrNil(obj *unstructured.Unstructured) client.Object {
+	if obj == nil {
+		return nil
+	}
+
+	return obj
+}
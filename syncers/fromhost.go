@@ -0,0 +1,544 @@
+package syncers
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/loft-sh/vcluster/pkg/constants"
+	"github.com/loft-sh/vcluster/pkg/patcher"
+	"github.com/loft-sh/vcluster/pkg/syncer"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	"github.com/loft-sh/vcluster/pkg/syncer/translator"
+	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
+	"github.com/loft-sh/vcluster/pkg/util/osutil"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ImportMode defines how changes flow between imported host objects and their virtual copies
+type ImportMode string
+
+const (
+	// ImportReadOnly keeps the virtual objects in sync with the host objects and reverts
+	// changes made in the virtual cluster
+	ImportReadOnly ImportMode = "ReadOnly"
+
+	// ImportBidirectional additionally syncs changes and deletions of the virtual objects
+	// back to the host objects
+	ImportBidirectional ImportMode = "Bidirectional"
+)
+
+// contentExcludedFields are the top level fields that are not part of the imported content
+var contentExcludedFields = []string{"apiVersion", "kind", "metadata", "status"}
+
+// FromHostOptions configure how host objects are imported into the virtual cluster
+type FromHostOptions struct {
+	// Name is the name of the syncer, defaults to from-host- and the lower case kind
+	Name string
+
+	// Mappings map host objects to virtual objects in the format of the vCluster config
+	// sync.fromHost.*.mappings.byName, e.g. "host-namespace/*": "virtual-namespace/*",
+	// "host-namespace/name": "virtual-namespace/name" or "": "virtual-namespace" for the
+	// objects of the vCluster host namespace.
+	Mappings map[string]string
+
+	// LabelSelector restricts the host objects that are imported
+	LabelSelector labels.Selector
+
+	// Mode defines how changes flow between host and virtual objects, defaults to ImportReadOnly
+	Mode ImportMode
+}
+
+// NewFromHost creates a syncer that imports the host objects of the given kind into the
+// virtual cluster. Labels, annotations and status are always synced from the host object,
+// all other fields depend on the import mode. Virtual namespaces are created as needed and
+// custom resource definitions are copied from the host cluster if the kind isn't known in
+// the virtual cluster.
+func NewFromHost(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind, options FromHostOptions) (syncertypes.Syncer, error) {
+	err := validateMappings(options.Mappings)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := options.Mode
+	if mode == "" {
+		mode = ImportReadOnly
+	} else if mode != ImportReadOnly && mode != ImportBidirectional {
+		return nil, fmt.Errorf("unsupported import mode %s", mode)
+	}
+
+	labelSelector := options.LabelSelector
+	if labelSelector == nil {
+		labelSelector = labels.Everything()
+	}
+
+	hasStatusSubresource, err := ensureKind(ctx, gvk)
+	if err != nil {
+		return nil, fmt.Errorf("ensure %s in virtual cluster: %w", gvk.String(), err)
+	}
+
+	fromHostTranslator, err := translator.NewFromHostTranslatorForGVK(ctx, gvk, options.Mappings)
+	if err != nil {
+		return nil, fmt.Errorf("create translator for %s: %w", gvk.String(), err)
+	}
+
+	name := options.Name
+	if name == "" {
+		name = fromHostTranslator.Name()
+	}
+
+	return &fromHostSyncer{
+		GenericTranslator: fromHostTranslator,
+
+		name:                 name,
+		mappings:             options.Mappings,
+		labelSelector:        labelSelector,
+		mode:                 mode,
+		hasStatusSubresource: hasStatusSubresource,
+	}, nil
+}
+
+type fromHostSyncer struct {
+	syncertypes.GenericTranslator
+
+	name                 string
+	mappings             map[string]string
+	labelSelector        labels.Selector
+	mode                 ImportMode
+	hasStatusSubresource bool
+}
+
+var _ syncertypes.Syncer = &fromHostSyncer{}
+
+var _ syncertypes.OptionsProvider = &fromHostSyncer{}
+
+var _ syncertypes.ObjectExcluder = &fromHostSyncer{}
+
+var _ syncertypes.ManagerProvider = &fromHostSyncer{}
+
+func (s *fromHostSyncer) Name() string {
+	return s.name
+}
+
+func (s *fromHostSyncer) Syncer() syncertypes.Sync[client.Object] {
+	return syncer.ToGenericSyncer(s)
+}
+
+func (s *fromHostSyncer) Options() *syncertypes.Options {
+	return &syncertypes.Options{
+		ObjectCaching: true,
+	}
+}
+
+func (s *fromHostSyncer) ExcludeVirtual(_ client.Object) bool {
+	return false
+}
+
+func (s *fromHostSyncer) ExcludePhysical(pObj client.Object) bool {
+	// objects synced to the host by any vCluster are never imported
+	if _, ok := pObj.GetLabels()[translate.MarkerLabel]; ok {
+		return true
+	}
+
+	return !s.labelSelector.Matches(labels.Set(pObj.GetLabels()))
+}
+
+func (s *fromHostSyncer) ConfigureAndStartManager(ctx *synccontext.RegisterContext) (*synccontext.RegisterContext, error) {
+	// cluster scoped objects are watched by the default host manager
+	if len(s.mappings) == 0 {
+		return ctx, nil
+	}
+
+	// the host manager only watches the vCluster host namespace, so other mapped namespaces
+	// need a manager of their own
+	hostManager, skip, err := syncer.ConfigureNewLocalManager(ctx, s.mappings, s.Name())
+	if err != nil {
+		return nil, err
+	} else if skip {
+		return ctx, nil
+	}
+	newCtx := *ctx
+
+	go func() {
+		err := hostManager.Start(newCtx)
+		if err != nil {
+			klog.Errorf("error starting host manager for %s syncer: %v", s.Name(), err)
+			osutil.Exit(1)
+		}
+	}()
+
+	if synced := hostManager.GetCache().WaitForCacheSync(newCtx); !synced {
+		return nil, fmt.Errorf("cache was not synced for host manager of %s syncer", s.Name())
+	}
+
+	newCtx.HostManager = hostManager
+	return &newCtx, nil
+}
+
+func (s *fromHostSyncer) SyncToHost(ctx *synccontext.SyncContext, event *synccontext.SyncToHostEvent[client.Object]) (ctrl.Result, error) {
+	// only delete virtual objects that were imported, all others are unrelated
+	if event.HostOld == nil && !s.isImported(ctx, event.Virtual) {
+		return ctrl.Result{}, nil
+	}
+
+	// host object is not here anymore, so we delete
+	return patcher.DeleteVirtualObject(ctx, event.Virtual, event.HostOld, "host object was deleted")
+}
+
+func (s *fromHostSyncer) Sync(ctx *synccontext.SyncContext, event *synccontext.SyncEvent[client.Object]) (_ ctrl.Result, retErr error) {
+	options := []patcher.Option{}
+	if s.mode == ImportReadOnly {
+		options = append(options, patcher.SkipHostPatch())
+	}
+	if !s.hasStatusSubresource {
+		options = append(options, patcher.NoStatusSubResource())
+	}
+
+	patchHelper, err := patcher.NewSyncerPatcher(ctx, event.Host, event.Virtual, options...)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("new syncer patcher: %w", err)
+	}
+
+	defer func() {
+		if err := patchHelper.Patch(ctx, event.Host, event.Virtual); err != nil {
+			retErr = utilerrors.NewAggregate([]error{retErr, err})
+		}
+		if retErr != nil {
+			s.EventRecorder().Eventf(event.Virtual, nil, "Warning", "SyncError", "SyncError", "Error syncing: %v", retErr)
+		}
+	}()
+
+	// any changes made below here are correctly synced
+
+	// sync metadata and status
+	event.Virtual.SetAnnotations(s.virtualAnnotations(event.Host, event.Virtual))
+	event.Virtual.SetLabels(translate.VirtualLabels(event.Host, event.Virtual))
+	err = copyTopLevelFields(event.Host, event.Virtual, "status")
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("sync status: %w", err)
+	}
+
+	// sync content
+	if s.mode == ImportReadOnly || event.HostOld == nil || event.VirtualOld == nil {
+		err = copyContent(event.Host, event.Virtual)
+	} else {
+		err = mergeContentBidirectional(event.VirtualOld, event.Virtual, event.HostOld, event.Host)
+	}
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("sync content: %w", err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (s *fromHostSyncer) SyncToVirtual(ctx *synccontext.SyncContext, event *synccontext.SyncToVirtualEvent[client.Object]) (ctrl.Result, error) {
+	if event.VirtualOld != nil {
+		if event.Host.GetDeletionTimestamp() != nil {
+			return patcher.DeleteVirtualObject(ctx, event.VirtualOld, event.Host, "host object was deleted")
+		}
+
+		// virtual object was deleted
+		if s.mode == ImportBidirectional {
+			return patcher.DeleteHostObject(ctx, event.Host, event.VirtualOld, "virtual object was deleted")
+		}
+	}
+
+	vObj := translate.VirtualMetadata(event.Host, s.HostToVirtual(ctx, types.NamespacedName{Name: event.Host.GetName(), Namespace: event.Host.GetNamespace()}, event.Host))
+	vObj.SetAnnotations(s.virtualAnnotations(event.Host, vObj))
+
+	result, ready, err := ensureVirtualNamespace(ctx, vObj.GetNamespace())
+	if !ready || err != nil {
+		return result, err
+	}
+
+	return patcher.CreateVirtualObject(ctx, event.Host, vObj, s.EventRecorder(), s.hasStatusSubresource)
+}
+
+// virtualAnnotations returns the annotations of the virtual object, which reference the host
+// object it was imported from
+func (s *fromHostSyncer) virtualAnnotations(pObj, vObj client.Object) map[string]string {
+	annotations := translate.VirtualAnnotations(pObj, vObj)
+	annotations[translate.HostNameAnnotation] = pObj.GetName()
+	annotations[translate.HostNamespaceAnnotation] = pObj.GetNamespace()
+	return annotations
+}
+
+// isImported checks if the virtual object was imported from a host object that is still
+// mapped to it. The old host object isn't cached after a restart, so this is checked
+// through the annotations.
+func (s *fromHostSyncer) isImported(ctx *synccontext.SyncContext, vObj client.Object) bool {
+	annotations := vObj.GetAnnotations()
+	if annotations[translate.HostNameAnnotation] == "" {
+		return false
+	}
+
+	hostName := types.NamespacedName{Namespace: annotations[translate.HostNamespaceAnnotation], Name: annotations[translate.HostNameAnnotation]}
+	virtualName := s.HostToVirtual(ctx, hostName, nil)
+	return virtualName.Name == vObj.GetName() && virtualName.Namespace == vObj.GetNamespace()
+}
+
+// ensureVirtualNamespace makes sure the namespace exists in the virtual cluster and returns
+// if objects can be created in it. Otherwise the returned result requeues the request.
+func ensureVirtualNamespace(ctx *synccontext.SyncContext, name string) (ctrl.Result, bool, error) {
+	namespace := &corev1.Namespace{}
+	err := ctx.VirtualClient.Get(ctx, client.ObjectKey{Name: name}, namespace)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return ctrl.Result{Requeue: true}, false, client.IgnoreAlreadyExists(ctx.VirtualClient.Create(ctx, &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+			}))
+		}
+
+		return ctrl.Result{}, false, err
+	} else if namespace.DeletionTimestamp != nil {
+		// cannot create objects in terminating namespaces, requeue to re-create the namespace later
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, false, nil
+	}
+
+	return ctrl.Result{}, true, nil
+}
+
+// ensureKind makes sure the kind is served in the virtual cluster and returns if it has a
+// status subresource. Custom resource definitions are copied from the host cluster.
+func ensureKind(ctx *synccontext.RegisterContext, gvk schema.GroupVersionKind) (bool, error) {
+	mapping, err := ctx.VirtualManager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		isClusterScoped, hasStatusSubresource, err := translate.EnsureCRDFromPhysicalCluster(ctx.Context, ctx.HostManager.GetConfig(), ctx.VirtualManager.GetConfig(), gvk)
+		if err != nil {
+			return false, err
+		} else if isClusterScoped {
+			return false, fmt.Errorf("%s is cluster scoped, only namespaced resources can be imported", gvk.String())
+		}
+
+		return hasStatusSubresource, nil
+	} else if err != nil {
+		return false, err
+	} else if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return false, fmt.Errorf("%s is cluster scoped, only namespaced resources can be imported", gvk.String())
+	}
+
+	return hasStatusSubresource(ctx, mapping.Resource)
+}
+
+// hasStatusSubresource checks through discovery if the resource has a status subresource
+// in the virtual cluster
+func hasStatusSubresource(ctx *synccontext.RegisterContext, resource schema.GroupVersionResource) (bool, error) {
+	return hasStatusSubresourceIn(ctx.VirtualManager.GetConfig(), resource)
+}
+
+func hasStatusSubresourceIn(config *rest.Config, resource schema.GroupVersionResource) (bool, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return false, err
+	}
+
+	resources, err := discoveryClient.ServerResourcesForGroupVersion(resource.GroupVersion().String())
+	if err != nil {
+		return false, fmt.Errorf("discover resources of %s: %w", resource.GroupVersion().String(), err)
+	}
+
+	for _, apiResource := range resources.APIResources {
+		if apiResource.Name == resource.Resource+"/status" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func validateMappings(mappings map[string]string) error {
+	if len(mappings) == 0 {
+		return fmt.Errorf("mappings are empty")
+	}
+
+	for host, virtual := range mappings {
+		if host == constants.VClusterNamespaceInHostMappingSpecialCharacter {
+			continue
+		}
+		if strings.Count(host, "/") != 1 || strings.Count(virtual, "/") != 1 {
+			return fmt.Errorf("mapping %s: %s is invalid, expected NAMESPACE/NAME, NAMESPACE/* or /NAME as key and NAMESPACE/NAME or NAMESPACE/* as value", host, virtual)
+		}
+		if strings.HasSuffix(host, "/*") != strings.HasSuffix(virtual, "/*") {
+			return fmt.Errorf("mapping %s: %s is invalid, either both or none of key and value have to match all objects of a namespace", host, virtual)
+		}
+	}
+
+	return nil
+}
+
+// copyContent copies all fields except metadata and status from one object to the other
+func copyContent(from, to client.Object) error {
+	content, err := objectContent(from)
+	if err != nil {
+		return err
+	}
+
+	return setObjectContent(to, content)
+}
+
+// mergeContentBidirectional merges the changes of the content of either the virtual or
+// the host object into the other one. If both changed, the virtual changes win.
+func mergeContentBidirectional(virtualOld, virtual, hostOld, host client.Object) error {
+	virtualOldContent, err := objectContent(virtualOld)
+	if err != nil {
+		return err
+	}
+	virtualContent, err := objectContent(virtual)
+	if err != nil {
+		return err
+	}
+	hostOldContent, err := objectContent(hostOld)
+	if err != nil {
+		return err
+	}
+	hostContent, err := objectContent(host)
+	if err != nil {
+		return err
+	}
+
+	if !equality.Semantic.DeepEqual(virtualOldContent, virtualContent) {
+		merged, err := mergeChangesInto(virtualOldContent, virtualContent, hostContent)
+		if err != nil {
+			return err
+		}
+
+		return setObjectContent(host, merged)
+	} else if !equality.Semantic.DeepEqual(hostOldContent, hostContent) {
+		merged, err := mergeChangesInto(hostOldContent, hostContent, virtualContent)
+		if err != nil {
+			return err
+		}
+
+		return setObjectContent(virtual, merged)
+	}
+
+	return nil
+}
+
+// mergeChangesInto applies the changes between oldContent and newContent as json merge
+// patch to outContent. Unlike patcher.MergeChangesInto it keeps integers as int64, so the
+// content can be converted back into typed objects.
+func mergeChangesInto(oldContent, newContent, outContent map[string]interface{}) (map[string]interface{}, error) {
+	oldBytes, err := json.Marshal(oldContent)
+	if err != nil {
+		return nil, fmt.Errorf("marshal old content: %w", err)
+	}
+	newBytes, err := json.Marshal(newContent)
+	if err != nil {
+		return nil, fmt.Errorf("marshal new content: %w", err)
+	}
+	outBytes, err := json.Marshal(outContent)
+	if err != nil {
+		return nil, fmt.Errorf("marshal content: %w", err)
+	}
+
+	patchBytes, err := jsonpatch.CreateMergePatch(oldBytes, newBytes)
+	if err != nil {
+		return nil, fmt.Errorf("create merge patch: %w", err)
+	}
+	mergedBytes, err := jsonpatch.MergePatch(outBytes, patchBytes)
+	if err != nil {
+		return nil, fmt.Errorf("merge patch: %w", err)
+	}
+
+	merged := map[string]interface{}{}
+	err = utiljson.Unmarshal(mergedBytes, &merged)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal merged content: %w", err)
+	}
+
+	return merged, nil
+}
+
+// objectContent returns the top level fields of the object except metadata and status
+func objectContent(obj client.Object) (map[string]interface{}, error) {
+	content, err := toUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range contentExcludedFields {
+		delete(content, field)
+	}
+
+	return content, nil
+}
+
+// setObjectContent replaces the top level fields of the object except metadata and status
+func setObjectContent(obj client.Object, content map[string]interface{}) error {
+	objMap, err := toUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	for field := range objMap {
+		if !slices.Contains(contentExcludedFields, field) {
+			delete(objMap, field)
+		}
+	}
+	for field, value := range content {
+		if !slices.Contains(contentExcludedFields, field) {
+			objMap[field] = runtime.DeepCopyJSONValue(value)
+		}
+	}
+
+	return fromUnstructured(objMap, obj)
+}
+
+// copyTopLevelFields copies the given top level fields from one object to the other
+func copyTopLevelFields(from, to client.Object, fields ...string) error {
+	fromMap, err := toUnstructured(from)
+	if err != nil {
+		return err
+	}
+	toMap, err := toUnstructured(to)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		err := copyField(&unstructured.Unstructured{Object: fromMap}, &unstructured.Unstructured{Object: toMap}, []string{field})
+		if err != nil {
+			return err
+		}
+	}
+
+	return fromUnstructured(toMap, to)
+}
+
+func toUnstructured(obj client.Object) (map[string]interface{}, error) {
+	if unstructuredObj, ok := obj.(*unstructured.Unstructured); ok {
+		return runtime.DeepCopyJSON(unstructuredObj.Object), nil
+	}
+
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+func fromUnstructured(objMap map[string]interface{}, obj client.Object) error {
+	if unstructuredObj, ok := obj.(*unstructured.Unstructured); ok {
+		unstructuredObj.Object = objMap
+		return nil
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(objMap, obj)
+}
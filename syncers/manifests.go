@@ -0,0 +1,458 @@
+package syncers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+	sprig "github.com/go-task/slim-sprig/v3"
+	"github.com/loft-sh/vcluster/pkg/scheme"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// InventoryLabel is set on all objects applied by a manifests syncer, its value is the name
+	// of the syncer
+	InventoryLabel = "vcluster.loft.sh/plugin-manifests"
+
+	// inventoryNamespace is the virtual namespace the inventory config maps are stored in
+	inventoryNamespace = "kube-system"
+
+	// inventoryKey is the config map key the inventory is stored under
+	inventoryKey = "inventory"
+)
+
+// ManifestsOptions configure the manifests applied in the virtual cluster
+type ManifestsOptions struct {
+	// Name identifies the manifests in the virtual cluster and is used as value of the
+	// InventoryLabel, defaults to manifests
+	Name string
+
+	// Paths are manifest files or directories with .yaml, .yml or .tpl files, which are read
+	// in lexical order
+	Paths []string
+
+	// Manifests are additional manifests, which are applied after the ones read from Paths
+	Manifests []string
+
+	// Values are passed to the templates, e.g. the plugin config
+	Values interface{}
+}
+
+// NewManifests creates a syncer that applies the given manifests in the virtual cluster.
+// Manifests are go templates that are rendered once with the given values and have the
+// sprig functions as well as toYaml available. Objects are applied with server-side apply,
+// re-applied whenever they are changed or deleted in the virtual cluster and pruned as soon
+// as they are removed from the manifests.
+func NewManifests(options ManifestsOptions) (syncertypes.Base, error) {
+	name := options.Name
+	if name == "" {
+		name = "manifests"
+	}
+
+	// manifests are keyed by their file name or index for errors
+	names := []string{}
+	manifests := []string{}
+	for _, path := range options.Paths {
+		files, err := manifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			manifest, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("read manifest %s: %w", file, err)
+			}
+
+			names = append(names, file)
+			manifests = append(manifests, string(manifest))
+		}
+	}
+	for i, manifest := range options.Manifests {
+		names = append(names, fmt.Sprintf("%s-%d", name, i))
+		manifests = append(manifests, manifest)
+	}
+
+	objects := []*unstructured.Unstructured{}
+	for i, manifest := range manifests {
+		rendered, err := renderManifest(names[i], manifest, options.Values)
+		if err != nil {
+			return nil, err
+		}
+
+		renderedObjects, err := decodeManifest(rendered)
+		if err != nil {
+			return nil, fmt.Errorf("decode manifest %s: %w", names[i], err)
+		}
+		objects = append(objects, renderedObjects...)
+	}
+
+	for _, obj := range objects {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[InventoryLabel] = name
+		obj.SetLabels(labels)
+	}
+
+	return &manifestsSyncer{
+		name:    name,
+		objects: objects,
+	}, nil
+}
+
+type manifestsSyncer struct {
+	name    string
+	objects []*unstructured.Unstructured
+
+	// namespacesDefaulted is true once the namespaced objects without a namespace were
+	// moved to the default namespace
+	namespacesDefaulted bool
+
+	virtualClient client.Client
+	restMapper    meta.RESTMapper
+}
+
+var _ syncertypes.ControllerStarter = &manifestsSyncer{}
+
+func (s *manifestsSyncer) Name() string {
+	return s.name
+}
+
+func (s *manifestsSyncer) Register(ctx *synccontext.RegisterContext) error {
+	// use a direct client, as the kinds of the manifests might not be cached
+	virtualClient, err := client.New(ctx.VirtualManager.GetConfig(), client.Options{
+		Scheme: scheme.Scheme,
+		Mapper: ctx.VirtualManager.GetRESTMapper(),
+	})
+	if err != nil {
+		return fmt.Errorf("create virtual client: %w", err)
+	}
+	s.virtualClient = virtualClient
+	s.restMapper = ctx.VirtualManager.GetRESTMapper()
+
+	// apply once before watching, so custom resource definitions of the manifests exist
+	err = s.sync(ctx.Context)
+	if err != nil {
+		return fmt.Errorf("apply manifests %s: %w", s.name, err)
+	}
+	klog.FromContext(ctx.Context).Info("Successfully applied manifests", "name", s.name, "objects", len(s.objects))
+
+	enqueue := handler.EnqueueRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: s.name}}}
+	})
+	owned := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return s.owns(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return s.owns(e.ObjectOld) || s.owns(e.ObjectNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return s.owns(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return s.owns(e.Object)
+		},
+	}
+
+	controllerBuilder := ctrl.NewControllerManagedBy(ctx.VirtualManager).Named(s.name)
+	watched := map[schema.GroupVersionKind]bool{}
+	for _, obj := range s.objects {
+		gvk := obj.GroupVersionKind()
+		if watched[gvk] {
+			continue
+		}
+		watched[gvk] = true
+
+		watchObj := &unstructured.Unstructured{}
+		watchObj.SetGroupVersionKind(gvk)
+		controllerBuilder = controllerBuilder.WatchesRawSource(source.Kind(ctx.VirtualManager.GetCache(), client.Object(watchObj), enqueue, owned))
+	}
+	if len(watched) == 0 {
+		return nil
+	}
+
+	return controllerBuilder.Complete(reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+		return reconcile.Result{}, s.sync(ctx)
+	}))
+}
+
+// sync applies all objects and prunes the objects of the last inventory that are not part
+// of the manifests anymore
+func (s *manifestsSyncer) sync(ctx context.Context) error {
+	for _, obj := range s.objects {
+		err := retry.OnError(retry.DefaultBackoff, meta.IsNoMatchError, func() error {
+			// the kinds of custom resources are only known once their definitions are
+			// applied, so the namespace is defaulted right before the object is applied
+			if !s.namespacesDefaulted {
+				err := s.defaultNamespace(obj)
+				if err != nil {
+					return err
+				}
+			}
+
+			return s.apply(ctx, obj)
+		})
+		if err != nil {
+			return fmt.Errorf("apply %s %s: %w", obj.GetKind(), objectKey(obj), err)
+		}
+	}
+	s.namespacesDefaulted = true
+
+	inventory, err := s.loadInventory(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := make([]inventoryEntry, 0, len(s.objects))
+	for _, obj := range s.objects {
+		current = append(current, newInventoryEntry(obj))
+	}
+
+	var errs []error
+	for _, entry := range staleInventoryEntries(inventory, current) {
+		err := s.prune(ctx, entry)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return s.saveInventory(ctx, current)
+}
+
+// defaultNamespace moves a namespaced object without namespace to the default namespace like
+// kubectl does. The object itself is changed, so the inventory records the namespace as well.
+func (s *manifestsSyncer) defaultNamespace(obj *unstructured.Unstructured) error {
+	if obj.GetNamespace() != "" {
+		return nil
+	}
+
+	mapping, err := s.restMapper.RESTMapping(obj.GroupVersionKind().GroupKind(), obj.GroupVersionKind().Version)
+	if err != nil {
+		return err
+	} else if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		obj.SetNamespace(metav1.NamespaceDefault)
+	}
+
+	return nil
+}
+
+func (s *manifestsSyncer) apply(ctx context.Context, obj *unstructured.Unstructured) error {
+	return s.virtualClient.Apply(ctx, client.ApplyConfigurationFromUnstructured(obj.DeepCopy()), client.FieldOwner(s.fieldManager()), client.ForceOwnership)
+}
+
+// prune deletes an object of a previous inventory, if it's still owned by the manifests
+func (s *manifestsSyncer) prune(ctx context.Context, entry inventoryEntry) error {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.FromAPIVersionAndKind(entry.APIVersion, entry.Kind))
+	err := s.virtualClient.Get(ctx, types.NamespacedName{Namespace: entry.Namespace, Name: entry.Name}, obj)
+	if kerrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get %s %s: %w", entry.Kind, entry.Name, err)
+	} else if !s.owns(obj) {
+		return nil
+	}
+
+	klog.FromContext(ctx).Info("Prune object removed from manifests", "name", s.name, "kind", entry.Kind, "object", objectKey(obj))
+	err = s.virtualClient.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("prune %s %s: %w", entry.Kind, entry.Name, err)
+	}
+
+	return nil
+}
+
+func (s *manifestsSyncer) loadInventory(ctx context.Context) ([]inventoryEntry, error) {
+	configMap := &corev1.ConfigMap{}
+	err := s.virtualClient.Get(ctx, types.NamespacedName{Namespace: inventoryNamespace, Name: s.inventoryName()}, configMap)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get inventory: %w", err)
+	}
+
+	inventory := []inventoryEntry{}
+	err = json.Unmarshal([]byte(configMap.Data[inventoryKey]), &inventory)
+	if err != nil {
+		return nil, fmt.Errorf("parse inventory: %w", err)
+	}
+
+	return inventory, nil
+}
+
+func (s *manifestsSyncer) saveInventory(ctx context.Context, inventory []inventoryEntry) error {
+	raw, err := json.Marshal(inventory)
+	if err != nil {
+		return err
+	}
+
+	configMap := &unstructured.Unstructured{}
+	configMap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	configMap.SetNamespace(inventoryNamespace)
+	configMap.SetName(s.inventoryName())
+	configMap.SetLabels(map[string]string{InventoryLabel: s.name})
+	err = unstructured.SetNestedField(configMap.Object, string(raw), "data", inventoryKey)
+	if err != nil {
+		return err
+	}
+
+	err = s.virtualClient.Apply(ctx, client.ApplyConfigurationFromUnstructured(configMap), client.FieldOwner(s.fieldManager()), client.ForceOwnership)
+	if err != nil {
+		return fmt.Errorf("save inventory: %w", err)
+	}
+
+	return nil
+}
+
+func (s *manifestsSyncer) owns(obj client.Object) bool {
+	return obj.GetLabels()[InventoryLabel] == s.name
+}
+
+func (s *manifestsSyncer) inventoryName() string {
+	return "vcluster-plugin-manifests-" + s.name
+}
+
+func (s *manifestsSyncer) fieldManager() string {
+	return "vcluster-plugin-" + s.name
+}
+
+// inventoryEntry references an object applied by a manifests syncer
+type inventoryEntry struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func newInventoryEntry(obj *unstructured.Unstructured) inventoryEntry {
+	return inventoryEntry{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// staleInventoryEntries returns the entries of the last inventory that are not part of the
+// current one anymore
+func staleInventoryEntries(last, current []inventoryEntry) []inventoryEntry {
+	stale := []inventoryEntry{}
+	for _, entry := range last {
+		if !slices.Contains(current, entry) {
+			stale = append(stale, entry)
+		}
+	}
+
+	return stale
+}
+
+// manifestFiles returns the path itself or the manifest files of the directory
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifests %s: %w", path, err)
+	} else if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifests %s: %w", path, err)
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		extension := filepath.Ext(entry.Name())
+		if entry.IsDir() || (extension != ".yaml" && extension != ".yml" && extension != ".tpl") {
+			continue
+		}
+
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+
+	return files, nil
+}
+
+func renderManifest(name, manifest string, values interface{}) ([]byte, error) {
+	tpl, err := template.New(name).Option("missingkey=error").Funcs(sprig.TxtFuncMap()).Funcs(template.FuncMap{
+		"toYaml": func(value interface{}) (string, error) {
+			out, err := yaml.Marshal(value)
+			return strings.TrimSuffix(string(out), "\n"), err
+		},
+	}).Parse(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("parse manifest template %s: %w", name, err)
+	}
+
+	out := &bytes.Buffer{}
+	err = tpl.Execute(out, values)
+	if err != nil {
+		return nil, fmt.Errorf("render manifest template %s: %w", name, err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// decodeManifest splits a rendered manifest into its objects, empty documents are skipped
+func decodeManifest(manifest []byte) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		} else if err != nil {
+			return nil, err
+		} else if len(obj.Object) == 0 {
+			continue
+		}
+
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("object %s is missing apiVersion, kind or name", objectKey(obj))
+		}
+		objects = append(objects, obj)
+	}
+}
+
+func objectKey(obj client.Object) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
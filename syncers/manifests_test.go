@@ -0,0 +1,194 @@
+package syncers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRenderManifest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		values   interface{}
+		expected string
+		err      string
+	}{
+		{
+			name:     "values",
+			manifest: "name: {{ .name }}",
+			values:   map[string]interface{}{"name": "test"},
+			expected: "name: test",
+		},
+		{
+			name:     "sprig functions",
+			manifest: `name: {{ .name | default "fallback" | upper }}`,
+			values:   map[string]interface{}{"name": ""},
+			expected: "name: FALLBACK",
+		},
+		{
+			name:     "to yaml",
+			manifest: "labels:\n  {{- toYaml .labels | nindent 2 }}",
+			values:   map[string]interface{}{"labels": map[string]interface{}{"a": "b"}},
+			expected: "labels:\n  a: b",
+		},
+		{
+			name:     "missing value",
+			manifest: "name: {{ .name }}",
+			values:   map[string]interface{}{},
+			err:      "render manifest template",
+		},
+		{
+			name:     "invalid template",
+			manifest: "name: {{ .name",
+			err:      "parse manifest template",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			out, err := renderManifest("test.yaml", testCase.manifest, testCase.values)
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if string(out) != testCase.expected {
+				t.Fatalf("expected %q, got %q", testCase.expected, string(out))
+			}
+		})
+	}
+}
+
+func TestDecodeManifest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		expected []string
+		err      string
+	}{
+		{
+			name:     "multiple documents",
+			manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: b\n  namespace: test\n",
+			expected: []string{"ConfigMap a", "Secret test/b"},
+		},
+		{
+			name:     "empty documents",
+			manifest: "---\n# comment\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\n",
+			expected: []string{"ConfigMap a"},
+		},
+		{
+			name:     "json",
+			manifest: `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}}`,
+			expected: []string{"ConfigMap a"},
+		},
+		{
+			name:     "missing name",
+			manifest: "apiVersion: v1\nkind: ConfigMap\n",
+			err:      "missing apiVersion, kind or name",
+		},
+		{
+			name:     "invalid yaml",
+			manifest: "apiVersion: [",
+			err:      "yaml",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			objects, err := decodeManifest([]byte(testCase.manifest))
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			decoded := []string{}
+			for _, obj := range objects {
+				decoded = append(decoded, obj.GetKind()+" "+objectKey(obj))
+			}
+			if !reflect.DeepEqual(decoded, testCase.expected) {
+				t.Fatalf("expected %v, got %v", testCase.expected, decoded)
+			}
+		})
+	}
+}
+
+func TestStaleInventoryEntries(t *testing.T) {
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	restMapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	s := &manifestsSyncer{restMapper: restMapper}
+
+	newObject := func(kind, namespace, name string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		return obj
+	}
+	configMap := inventoryEntry{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "a"}
+	namespace := inventoryEntry{APIVersion: "v1", Kind: "Namespace", Name: "test"}
+
+	testCases := []struct {
+		name     string
+		last     []inventoryEntry
+		objects  []*unstructured.Unstructured
+		expected []inventoryEntry
+	}{
+		{
+			name:     "unchanged objects with defaulted namespace",
+			last:     []inventoryEntry{configMap, namespace},
+			objects:  []*unstructured.Unstructured{newObject("ConfigMap", "", "a"), newObject("Namespace", "", "test")},
+			expected: []inventoryEntry{},
+		},
+		{
+			name:     "removed object",
+			last:     []inventoryEntry{configMap, namespace},
+			objects:  []*unstructured.Unstructured{newObject("Namespace", "", "test")},
+			expected: []inventoryEntry{configMap},
+		},
+		{
+			name:     "moved object",
+			last:     []inventoryEntry{configMap},
+			objects:  []*unstructured.Unstructured{newObject("ConfigMap", "other", "a")},
+			expected: []inventoryEntry{configMap},
+		},
+		{
+			name:     "empty inventory",
+			objects:  []*unstructured.Unstructured{newObject("ConfigMap", "", "a")},
+			expected: []inventoryEntry{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			current := []inventoryEntry{}
+			for _, obj := range testCase.objects {
+				err := s.defaultNamespace(obj)
+				if err != nil {
+					t.Fatalf("default namespace: %v", err)
+				}
+
+				current = append(current, newInventoryEntry(obj))
+			}
+
+			stale := staleInventoryEntries(testCase.last, current)
+			if !reflect.DeepEqual(stale, testCase.expected) {
+				t.Fatalf("expected %v, got %v", testCase.expected, stale)
+			}
+		})
+	}
+}
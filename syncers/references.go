@@ -0,0 +1,208 @@
+package syncers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/loft-sh/vcluster/pkg/mappings"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reference declares a field that references another object of the same namespace by name,
+// e.g. a secret
+type Reference struct {
+	// Path is the JSONPath of the name field, e.g. spec.secretName. A * or [*] segment matches
+	// all items of a list or all values of a map, e.g. spec.volumes[*].configMap.name.
+	Path string
+
+	// GroupVersionKind is the kind of the referenced object. vCluster needs to have a mapper
+	// for it, e.g. by adding it to Options.RegisterMappings.
+	GroupVersionKind schema.GroupVersionKind
+}
+
+// References translates the names of referenced objects between the virtual and the host
+// cluster. References are recorded in the mappings store, so vCluster syncs the referenced
+// objects to the host as long as the referencing object exists.
+type References struct {
+	references []reference
+}
+
+type reference struct {
+	path []string
+	gvk  schema.GroupVersionKind
+}
+
+// NewReferences creates new references. Needs to be called after the mappers of the
+// referenced kinds are registered. Returns nil if no references are given.
+func NewReferences(ctx *synccontext.RegisterContext, references ...Reference) (*References, error) {
+	if len(references) == 0 {
+		return nil, nil
+	}
+
+	parsed := make([]reference, 0, len(references))
+	for _, ref := range references {
+		if !ctx.Mappings.Has(ref.GroupVersionKind) {
+			return nil, fmt.Errorf("reference %s: no mapper registered for %s", ref.Path, ref.GroupVersionKind.String())
+		}
+
+		path, err := parseReferencePath(ref.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, reference{path: path, gvk: ref.GroupVersionKind})
+	}
+
+	return &References{references: parsed}, nil
+}
+
+// ToHost translates the references of obj from virtual to host names and records them as
+// references of the owner mapping. It returns an error if a referenced name has no host
+// name, so the sync is retried later.
+func (r *References) ToHost(ctx *synccontext.SyncContext, owner synccontext.NameMapping, obj client.Object) error {
+	return r.toHost(ctx, owner, obj, true)
+}
+
+func (r *References) toHost(ctx *synccontext.SyncContext, owner synccontext.NameMapping, obj client.Object, record bool) error {
+	var done func() error
+	if record {
+		done = func() error {
+			// the sync context only saves the mapping of the object it syncs
+			if current, ok := synccontext.MappingFrom(ctx); ok && current.Equals(owner) {
+				return nil
+			}
+
+			return ctx.Mappings.Store().SaveMapping(ctx, owner)
+		}
+	}
+
+	return r.translate(obj, func(ref reference, name string) (string, error) {
+		// never fall back to the virtual name, it could reference an unrelated host object
+		hostName := mappings.VirtualToHost(ctx, name, owner.VirtualName.Namespace, ref.gvk)
+		if hostName.Name == "" {
+			return "", fmt.Errorf("%s %s/%s referenced by %s has no host name", ref.gvk.Kind, owner.VirtualName.Namespace, name, strings.Join(ref.path, "."))
+		} else if !record {
+			return hostName.Name, nil
+		}
+
+		err := ctx.Mappings.Store().AddReference(ctx, synccontext.NameMapping{
+			GroupVersionKind: ref.gvk,
+			VirtualName:      types.NamespacedName{Namespace: owner.VirtualName.Namespace, Name: name},
+			HostName:         hostName,
+		}, owner)
+		if err != nil {
+			return "", fmt.Errorf("add reference to %s %s: %w", ref.gvk.Kind, name, err)
+		}
+
+		return hostName.Name, nil
+	}, done)
+}
+
+// ToVirtual translates the references of obj from host to virtual names. It returns an
+// error if a referenced name has no virtual name.
+func (r *References) ToVirtual(ctx *synccontext.SyncContext, owner synccontext.NameMapping, obj client.Object) error {
+	return r.translate(obj, func(ref reference, name string) (string, error) {
+		virtualName := mappings.HostToVirtual(ctx, name, owner.HostName.Namespace, nil, ref.gvk)
+		if virtualName.Name == "" {
+			return "", fmt.Errorf("%s %s/%s referenced by %s has no virtual name", ref.gvk.Kind, owner.HostName.Namespace, name, strings.Join(ref.path, "."))
+		}
+
+		return virtualName.Name, nil
+	}, nil)
+}
+
+func (r *References) translate(obj client.Object, translateName func(ref reference, name string) (string, error), done func() error) error {
+	if len(r.references) == 0 {
+		return nil
+	}
+
+	objMap, err := toUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	translated := false
+	for _, ref := range r.references {
+		_, err := walkReferences(objMap, ref.path, func(name string) (string, error) {
+			translated = true
+			return translateName(ref, name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !translated {
+		return nil
+	}
+
+	err = fromUnstructured(objMap, obj)
+	if err != nil {
+		return err
+	}
+	if done != nil {
+		return done()
+	}
+
+	return nil
+}
+
+// walkReferences calls translateName for every non-empty string at the path and replaces
+// it with the returned name
+func walkReferences(value interface{}, path []string, translateName func(name string) (string, error)) (interface{}, error) {
+	if len(path) == 0 {
+		name, ok := value.(string)
+		if !ok || name == "" {
+			return value, nil
+		}
+
+		return translateName(name)
+	}
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typedValue {
+			if path[0] != "*" && path[0] != key {
+				continue
+			}
+
+			newChild, err := walkReferences(child, path[1:], translateName)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[key] = newChild
+		}
+	case []interface{}:
+		if path[0] != "*" {
+			return value, nil
+		}
+
+		for i, child := range typedValue {
+			newChild, err := walkReferences(child, path[1:], translateName)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[i] = newChild
+		}
+	}
+
+	return value, nil
+}
+
+// parseReferencePath splits a JSONPath into its segments
+func parseReferencePath(path string) ([]string, error) {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	trimmed = strings.TrimPrefix(strings.TrimPrefix(trimmed, "$"), ".")
+	trimmed = strings.ReplaceAll(trimmed, "[*]", ".*")
+	segments := strings.Split(trimmed, ".")
+	if slices.Contains(segments, "") {
+		return nil, fmt.Errorf("reference path %q is invalid", path)
+	} else if segments[0] == "metadata" || segments[0] == "apiVersion" || segments[0] == "kind" {
+		return nil, fmt.Errorf("reference path %q is invalid, references can't be part of %s", path, segments[0])
+	}
+
+	return segments, nil
+}
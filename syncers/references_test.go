@@ -0,0 +1,141 @@
+package syncers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var secretGVK = schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+
+func TestReferencesTranslate(t *testing.T) {
+	references := &References{references: []reference{
+		{path: []string{"spec", "secretName"}, gvk: secretGVK},
+		{path: []string{"spec", "volumes", "*", "secret"}, gvk: secretGVK},
+	}}
+	mapper := &nameMapper{
+		gvk: secretGVK,
+		virtualToHost: map[types.NamespacedName]types.NamespacedName{
+			{Namespace: "default", Name: "a"}: {Namespace: "host", Name: "a-x-default"},
+			{Namespace: "default", Name: "b"}: {Namespace: "host", Name: "b-x-default"},
+		},
+	}
+	ctx := &synccontext.SyncContext{Mappings: &mappingsRegistry{mappers: map[schema.GroupVersionKind]synccontext.Mapper{secretGVK: mapper}}}
+	owner := synccontext.NameMapping{
+		VirtualName: types.NamespacedName{Namespace: "default", Name: "owner"},
+		HostName:    types.NamespacedName{Namespace: "host", Name: "owner-x-default"},
+	}
+
+	testCases := []struct {
+		name        string
+		toHost      bool
+		spec        map[string]interface{}
+		expected    map[string]interface{}
+		expectedErr string
+	}{
+		{
+			name:     "to host",
+			toHost:   true,
+			spec:     map[string]interface{}{"secretName": "a", "volumes": []interface{}{map[string]interface{}{"secret": "b"}}},
+			expected: map[string]interface{}{"secretName": "a-x-default", "volumes": []interface{}{map[string]interface{}{"secret": "b-x-default"}}},
+		},
+		{
+			name:     "to virtual",
+			spec:     map[string]interface{}{"secretName": "a-x-default", "volumes": []interface{}{map[string]interface{}{"secret": "b-x-default"}}},
+			expected: map[string]interface{}{"secretName": "a", "volumes": []interface{}{map[string]interface{}{"secret": "b"}}},
+		},
+		{
+			name:     "empty and missing references",
+			toHost:   true,
+			spec:     map[string]interface{}{"secretName": ""},
+			expected: map[string]interface{}{"secretName": ""},
+		},
+		{
+			name:        "no host name",
+			toHost:      true,
+			spec:        map[string]interface{}{"secretName": "unknown"},
+			expectedErr: "has no host name",
+		},
+		{
+			name:        "no virtual name",
+			spec:        map[string]interface{}{"volumes": []interface{}{map[string]interface{}{"secret": "other-tenant"}}},
+			expectedErr: "has no virtual name",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Example",
+				"metadata":   map[string]interface{}{"name": "owner", "namespace": "default"},
+				"spec":       testCase.spec,
+			}}
+
+			var err error
+			if testCase.toHost {
+				err = references.toHost(ctx, owner, obj, false)
+			} else {
+				err = references.ToVirtual(ctx, owner, obj)
+			}
+			if testCase.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", testCase.expectedErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if !reflect.DeepEqual(obj.Object["spec"], testCase.expected) {
+				t.Fatalf("expected %v, got %v", testCase.expected, obj.Object["spec"])
+			}
+		})
+	}
+}
+
+// nameMapper translates the names in virtualToHost and returns an empty name for all others
+type nameMapper struct {
+	synccontext.Mapper
+
+	gvk           schema.GroupVersionKind
+	virtualToHost map[types.NamespacedName]types.NamespacedName
+}
+
+func (m *nameMapper) GroupVersionKind() schema.GroupVersionKind {
+	return m.gvk
+}
+
+func (m *nameMapper) VirtualToHost(_ *synccontext.SyncContext, req types.NamespacedName, _ client.Object) types.NamespacedName {
+	return m.virtualToHost[req]
+}
+
+func (m *nameMapper) HostToVirtual(_ *synccontext.SyncContext, req types.NamespacedName, _ client.Object) types.NamespacedName {
+	for virtualName, hostName := range m.virtualToHost {
+		if hostName == req {
+			return virtualName
+		}
+	}
+
+	return types.NamespacedName{}
+}
+
+type mappingsRegistry struct {
+	synccontext.MappingsRegistry
+
+	mappers map[schema.GroupVersionKind]synccontext.Mapper
+}
+
+func (m *mappingsRegistry) ByGVK(gvk schema.GroupVersionKind) (synccontext.Mapper, error) {
+	return m.mappers[gvk], nil
+}
+
+func (m *mappingsRegistry) Has(gvk schema.GroupVersionKind) bool {
+	return m.mappers[gvk] != nil
+}
@@ -0,0 +1,251 @@
+package syncers
+
+import (
+	"fmt"
+
+	"github.com/loft-sh/vcluster/pkg/patcher"
+	"github.com/loft-sh/vcluster/pkg/scheme"
+	"github.com/loft-sh/vcluster/pkg/syncer"
+	"github.com/loft-sh/vcluster/pkg/syncer/synccontext"
+	"github.com/loft-sh/vcluster/pkg/syncer/translator"
+	syncertypes "github.com/loft-sh/vcluster/pkg/syncer/types"
+	"github.com/loft-sh/vcluster/pkg/util/clienthelper"
+	"github.com/loft-sh/vcluster/pkg/util/translate"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// StatusOptions configure a status syncer
+type StatusOptions struct {
+	// Annotations are the keys of host annotations that are synced back to the virtual object
+	// together with the status
+	Annotations []string
+
+	// Adoption defines what happens to host objects without a virtual object that were
+	// left by a previous vCluster, defaults to AdoptionDelete
+	Adoption AdoptionPolicy
+}
+
+// NewStatusSyncer creates a syncer for the kind of the mapper, where the virtual object is
+// the source of truth for the spec and a host controller fills in the status. The spec and
+// metadata are synced to the host object, the status and the configured annotations back to
+// the virtual object. Only changes of the host status since the last sync are applied, so
+// concurrent edits of the virtual status are kept. If the kind has no status subresource,
+// the virtual object is updated as a whole. The mapper needs to be registered by the caller.
+func NewStatusSyncer[T client.Object](ctx *synccontext.RegisterContext, name string, mapper synccontext.Mapper, options StatusOptions) (syncertypes.Syncer, error) {
+	gvk := mapper.GroupVersionKind()
+
+	var obj client.Object
+	if _, ok := any(*new(T)).(*unstructured.Unstructured); ok {
+		unstructuredObj := &unstructured.Unstructured{}
+		unstructuredObj.SetGroupVersionKind(gvk)
+		obj = unstructuredObj
+	} else {
+		runtimeObj, err := scheme.Scheme.New(gvk)
+		if err != nil {
+			return nil, fmt.Errorf("create object for %s: %w", gvk.String(), err)
+		}
+
+		typedObj, ok := runtimeObj.(T)
+		if !ok {
+			return nil, fmt.Errorf("kind %s of mapper %T does not match the syncer type %T", gvk.String(), mapper, *new(T))
+		}
+		obj = typedObj
+	}
+
+	mapping, err := ctx.VirtualManager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("find resource for %s: %w", gvk.String(), err)
+	}
+	hasStatusSubresource, err := hasStatusSubresource(ctx, mapping.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	genericTranslator := translator.NewGenericTranslator(ctx, name, obj, mapper)
+	adopter, err := NewAdopter(gvk, options.Adoption, genericTranslator.EventRecorder())
+	if err != nil {
+		return nil, err
+	}
+
+	return &statusSyncer[T]{
+		GenericTranslator: genericTranslator,
+		Adopter:           adopter,
+
+		annotations:          options.Annotations,
+		hasStatusSubresource: hasStatusSubresource,
+	}, nil
+}
+
+type statusSyncer[T client.Object] struct {
+	syncertypes.GenericTranslator
+	*Adopter
+
+	annotations          []string
+	hasStatusSubresource bool
+}
+
+var _ syncertypes.OptionsProvider = &statusSyncer[client.Object]{}
+
+var _ syncertypes.Importer = &statusSyncer[client.Object]{}
+
+func (s *statusSyncer[T]) Options() *syncertypes.Options {
+	// the old host objects are needed to find the status changes
+	return &syncertypes.Options{
+		ObjectCaching:      true,
+		DisableUIDDeletion: s.DisableUIDDeletion(),
+	}
+}
+
+func (s *statusSyncer[T]) Syncer() syncertypes.Sync[client.Object] {
+	return syncer.ToGenericSyncer[T](s)
+}
+
+func (s *statusSyncer[T]) SyncToHost(ctx *synccontext.SyncContext, event *synccontext.SyncToHostEvent[T]) (ctrl.Result, error) {
+	pObj := translate.HostMetadata(event.Virtual, s.VirtualToHost(ctx, types.NamespacedName{Name: event.Virtual.GetName(), Namespace: event.Virtual.GetNamespace()}, event.Virtual))
+
+	// the status is filled in by the host controller
+	hostMap, err := toUnstructured(pObj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	delete(hostMap, "status")
+	err = fromUnstructured(hostMap, pObj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return patcher.CreateHostObject(ctx, event.Virtual, pObj, s.EventRecorder(), s.hasStatusSubresource)
+}
+
+func (s *statusSyncer[T]) Sync(ctx *synccontext.SyncContext, event *synccontext.SyncEvent[T]) (_ ctrl.Result, retErr error) {
+	err := s.Takeover(ctx, event.Virtual, event.Host)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var options []patcher.Option
+	if !s.hasStatusSubresource {
+		options = append(options, patcher.NoStatusSubResource())
+	}
+
+	patchHelper, err := patcher.NewSyncerPatcher(ctx, event.Host, event.Virtual, options...)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("new syncer patcher: %w", err)
+	}
+
+	defer func() {
+		if err := patchHelper.Patch(ctx, event.Host, event.Virtual); err != nil {
+			retErr = utilerrors.NewAggregate([]error{retErr, err})
+		}
+		if retErr != nil {
+			s.EventRecorder().Eventf(event.Virtual, nil, "Warning", "SyncError", "SyncError", "Error syncing: %v", retErr)
+		}
+	}()
+
+	// any changes made below here are correctly synced
+
+	// sync metadata and spec to host
+	event.Host.SetAnnotations(translate.HostAnnotations(event.Virtual, event.Host, s.annotations...))
+	event.Host.SetLabels(translate.HostLabels(event.Virtual, event.Host))
+	err = copyContent(event.Virtual, event.Host)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("sync spec: %w", err)
+	}
+
+	// sync status and annotations back to virtual
+	var hostOld client.Object
+	if !clienthelper.IsNilObject(event.HostOld) {
+		hostOld = event.HostOld
+	}
+	err = s.syncStatus(hostOld, event.Host, event.Virtual)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("sync status: %w", err)
+	}
+	s.syncAnnotations(hostOld, event.Host, event.Virtual)
+
+	return ctrl.Result{}, nil
+}
+
+func (s *statusSyncer[T]) SyncToVirtual(ctx *synccontext.SyncContext, event *synccontext.SyncToVirtualEvent[T]) (ctrl.Result, error) {
+	result, handled, err := s.HandleOrphan(ctx, event.Host, event.VirtualOld, s.hasStatusSubresource)
+	if handled {
+		return result, err
+	}
+
+	// virtual object is not here anymore, so we delete
+	return patcher.DeleteHostObject(ctx, event.Host, event.VirtualOld, "virtual object was deleted")
+}
+
+// syncStatus applies the changes of the host status since the last sync to the virtual
+// status. Without an old host object the host status is copied.
+func (s *statusSyncer[T]) syncStatus(hostOld, host, virtual client.Object) error {
+	if hostOld == nil {
+		return copyTopLevelFields(host, virtual, "status")
+	}
+
+	hostOldMap, err := toUnstructured(hostOld)
+	if err != nil {
+		return err
+	}
+	hostMap, err := toUnstructured(host)
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(hostOldMap["status"], hostMap["status"]) {
+		return nil
+	}
+
+	virtualMap, err := toUnstructured(virtual)
+	if err != nil {
+		return err
+	}
+	merged, err := mergeChangesInto(
+		map[string]interface{}{"status": hostOldMap["status"]},
+		map[string]interface{}{"status": hostMap["status"]},
+		map[string]interface{}{"status": virtualMap["status"]},
+	)
+	if err != nil {
+		return err
+	}
+
+	virtualMap["status"] = merged["status"]
+	if merged["status"] == nil {
+		delete(virtualMap, "status")
+	}
+	return fromUnstructured(virtualMap, virtual)
+}
+
+// syncAnnotations copies the configured annotations that changed in the host object since
+// the last sync to the virtual object
+func (s *statusSyncer[T]) syncAnnotations(hostOld, host, virtual client.Object) {
+	if len(s.annotations) == 0 {
+		return
+	}
+
+	annotations := virtual.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for _, key := range s.annotations {
+		value, ok := host.GetAnnotations()[key]
+		if hostOld != nil {
+			oldValue, oldOk := hostOld.GetAnnotations()[key]
+			if value == oldValue && ok == oldOk {
+				continue
+			}
+		}
+
+		if ok {
+			annotations[key] = value
+		} else {
+			delete(annotations, key)
+		}
+	}
+
+	virtual.SetAnnotations(annotations)
+}